@@ -1,20 +1,35 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
-	"syscall"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/golang/glog"
 	"github.com/gomarkdown/markdown"
@@ -22,24 +37,102 @@ import (
 	"github.com/google/go-github/v27/github"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/jessevdk/go-flags"
+	nethtml "golang.org/x/net/html"
 	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
 )
 
-var Options struct {
+// options are the command line parameters, env vars and config file values
+type options struct {
+	Config            string        `long:"config" env:"CONFIG_FILE" description:"INI file with options, CLI parameters take precedence. It is re-read by /admin/reload."`
 	Listen            int           `short:"p" long:"listen" env:"LISTEN_PORT" required:"true" description:"Listen is started on this port."`
-	GitHubAccessToken string        `short:"t" long:"ghatoken" env:"GITHUB_ACCESS_TOKEN" required:"true" description:"Token for identifing the application."`
+	GitHubAccessToken string        `short:"t" long:"ghatoken" env:"GITHUB_ACCESS_TOKEN" required:"true" secret:"true" description:"Token for identifing the application."`
 	GitHubOrg         string        `short:"o" long:"ghorg" env:"GITHUB_ORG" required:"true" description:"GitHub Org being fetched for Repositories."`
+	GitHubApiUrl      string        `long:"ghapiurl" default:"https://api.github.com/" env:"GITHUB_API_URL" required:"false" description:"GitHub API the repositories are fetched from."`
+	JenkinsUrl        string        `long:"jenkinsurl" default:"https://jenkins-terraform.mesosphere.com/service/dcos-terraform-jenkins" env:"JENKINS_URL" required:"false" description:"Jenkins being queried for the build status."`
+	JenkinsNoRedirect bool          `long:"jenkins-no-redirect" env:"JENKINS_NO_REDIRECT" description:"Don't follow redirects of Jenkins, e.g. to the login page of an auth proxy, and show not run instead."`
+	ShowBuildNumber   bool          `long:"show-build-number" env:"SHOW_BUILD_NUMBER" description:"Show the number of the last build next to the badges, requires --jenkins-mode=json or multibranch."`
+	ShowRecentChanges bool          `long:"show-recent-changes" env:"SHOW_RECENT_CHANGES" description:"Show the last state transitions at the bottom of the page."`
+	RecentChanges     int           `long:"recent-changes" default:"10" env:"RECENT_CHANGES" required:"false" description:"Number of state transitions being kept."`
+	Unstable          bool          `long:"unstable" env:"UNSTABLE" description:"Show unstable builds, e.g. with failed tests, as unstable instead of not run."`
+	JenkinsMode       string        `long:"jenkins-mode" default:"text" choice:"text" choice:"json" choice:"multibranch" env:"JENKINS_MODE" required:"false" description:"text parses buildStatus/text, json reads the result of lastBuild/api/json, multibranch reads the last builds of all branches of a repo at once."`
+	CiBackend         string        `long:"ci-backend" default:"jenkins" choice:"jenkins" choice:"actions-badge" env:"CI_BACKEND" required:"false" description:"jenkins scrapes the build status, actions-badge embeds the native GitHub Actions badges without scraping."`
+	ActionsWorkflow   string        `long:"actions-workflow" default:"ci.yml" env:"ACTIONS_WORKFLOW" required:"false" description:"Workflow file the GitHub Actions badges are shown for."`
 	GitHubRepoPrefix  string        `long:"ghreporefresh" default:"terraform-" env:"GITHUB_REPO_PREFIX" required:"false" description:"GitHub repo prefix."`
 	GitHubOrgRefresh  time.Duration `long:"ghorgrefresh" default:"60m" env:"GITHUB_ORG_REFRESH" required:"false" description:"Time the GitHub Org being fetched repos from."`
 	CiStatusRefresh   time.Duration `long:"cistatusrefresh" default:"3m" env:"CI_STATUS_REFRESH" required:"false" description:"Time the CI status is being fetched."`
-	Timeout           time.Duration `long:"timeout" env:"TIMEOUT" description:"Duration for which the server gracefully wait for existing connections to finish - e.g. 15s or 1m"`
+	RefreshJitter     time.Duration `long:"refresh-jitter" env:"REFRESH_JITTER" description:"Randomizes every refresh interval by up to +- the jitter, spreading the upstream load of multiple instances."`
+	MinRefresh        time.Duration `long:"min-refresh" default:"30s" env:"MIN_REFRESH" required:"false" description:"Lower bound for all refresh intervals, shorter ones are raised to it. Only lower it if upstreams can take the load."`
+	Strict            bool          `long:"strict" env:"STRICT" description:"Exit if the generated HTML is malformed instead of logging a warning."`
+	WarmupTimeout     time.Duration `long:"warmup-timeout" default:"2m" env:"WARMUP_TIMEOUT" required:"false" description:"Maximum time the initial CI scrape delays readiness, not run badges are shown for the rest. 0 waits forever."`
+	LoadingPage       bool          `long:"loading-page" env:"LOADING_PAGE" description:"Show the progress of the initial refresh in a self reloading page instead of a bare 503."`
+	RetryAfter        time.Duration `long:"retry-after" default:"10s" env:"RETRY_AFTER" required:"false" description:"Retry-After sent with 503 while the initial refresh is running."`
+	MaxHeaderBytes    int           `long:"max-header-bytes" default:"1048576" env:"MAX_HEADER_BYTES" required:"false" description:"Maximum size of request headers."`
+	MaxBodyBytes      int64         `long:"max-body-bytes" default:"65536" env:"MAX_BODY_BYTES" required:"false" description:"Maximum size of request bodies of write endpoints."`
+	MaxRepos          int           `long:"max-repos" env:"MAX_REPOS" description:"Maximum number of repos shown, ordered by provider and name. 0 shows all."`
+	RenderCacheSize   int           `long:"render-cache-size" default:"64" env:"RENDER_CACHE_SIZE" required:"false" description:"Number of rendered, e.g. provider filtered, HTML views being cached."`
+	AssetsCheck       time.Duration `long:"assets-check" env:"ASSETS_CHECK" description:"Interval the static dir and CSS file are checked for being readable, reported by /health/assets. 0 disables the check."`
+	Timeout           time.Duration `long:"timeout" env:"TIMEOUT" description:"Duration for which the server gracefully wait for existing connections to finish - e.g. 15s or 1m. Deprecated, use --shutdown-grace."`
+	ShutdownGrace     time.Duration `long:"shutdown-grace" env:"SHUTDOWN_GRACE" description:"Time in-flight requests get to finish on shutdown, the refresh loops are stopped immediately. Defaults to --timeout."`
+	Providers         []string      `long:"provider" default:"aws" default:"azurerm" default:"gcp" default:"null" default:"template" env:"PROVIDERS" env-delim:"," required:"false" description:"Provider shown as section, can be repeated."`
+	PrimaryBranch     string        `long:"primary-branch" env:"PRIMARY_BRANCH" description:"Branch emphasized in the tables and used for the state of a repo. Defaults to the first branch."`
+	Branches          []string      `long:"branch" default:"support/0.2.x" default:"support/0.1.x" env:"BRANCHES" env-delim:"," required:"false" description:"Branch shown as status column, can be repeated."`
+	GroupBy           string        `long:"group-by" default:"provider" choice:"provider" choice:"provider,release" choice:"release,provider" env:"GROUP_BY" required:"false" description:"Grouping of the status tables, provider,release and release,provider add a second level."`
+	UnknownProvider   string        `long:"unknown-provider" env:"UNKNOWN_PROVIDER" description:"Section name collecting module repos of providers not configured, e.g. other. They are dropped if empty."`
+	AlwaysInclude     []string      `long:"always-include" env:"ALWAYS_INCLUDE" env-delim:"," description:"Exact repo name shown even if it matches no provider, can be repeated."`
+	AlwaysSection     string        `long:"always-include-section" default:"other" env:"ALWAYS_INCLUDE_SECTION" required:"false" description:"Section the --always-include repos are shown in, may be a provider."`
+	PruneEmptyColumns bool          `long:"prune-empty-columns" env:"PRUNE_EMPTY_COLUMNS" description:"Omit branch columns of a provider if no repo has a build for the branch."`
+	NoRepoLinks       bool          `long:"no-repo-links" env:"NO_REPO_LINKS" description:"Don't link the repo names to their GitHub repository."`
+	StickyStatus      bool          `long:"sticky-status" env:"STICKY_STATUS" description:"Show the last known state marked as stale if fetching a build status fails."`
+	ShowOpenPrs       bool          `long:"show-open-prs" env:"SHOW_OPEN_PRS" description:"Show the number of open pull requests per repo, refreshed with the GitHub org."`
+	ExtraCss          []string      `long:"extra-css" env:"EXTRA_CSS" env-delim:"," description:"Stylesheet under the static dir added after bootstrap, e.g. css/custom.css, can be repeated."`
+	RepoBadges        []string      `long:"repo-badge" env:"REPO_BADGES" env-delim:"," description:"Informational badge repo=label shown next to the repo name, e.g. terraform-aws-vpc=deprecated, can be repeated."`
+	BranchAliases     []string      `long:"branch-alias" env:"BRANCH_ALIASES" env-delim:"," description:"Shorter column header branch=alias, e.g. support/0.2.x=0.2, can be repeated."`
+	LatestBranchOnly  bool          `long:"latest-branch-only" env:"LATEST_BRANCH_ONLY" description:"Only show the newest release line of the configured branches."`
+	ColorNotrun       string        `long:"color-notrun" env:"COLOR_NOTRUN" description:"Color of generated not run badges, e.g. #9f9f9f. Setting any color switches to generated badges."`
+	ColorPassing      string        `long:"color-passing" env:"COLOR_PASSING" description:"Color of generated passing badges, e.g. #44cc11."`
+	ColorRunning      string        `long:"color-running" env:"COLOR_RUNNING" description:"Color of generated running badges, e.g. #007ec6."`
+	ColorFailing      string        `long:"color-failing" env:"COLOR_FAILING" description:"Color of generated failing badges, e.g. #e05d44."`
+	ColorAborted      string        `long:"color-aborted" env:"COLOR_ABORTED" description:"Color of generated aborted badges, e.g. #9f9f9f."`
+	ColorUnstable     string        `long:"color-unstable" env:"COLOR_UNSTABLE" description:"Color of generated unstable badges, e.g. #dfb317."`
+	ProviderCiRefresh []string      `long:"provider-ci-refresh" env:"PROVIDER_CI_REFRESH" env-delim:"," description:"Provider specific provider=duration overriding --cistatusrefresh, can be repeated."`
+	StatsdAddress     string        `long:"statsd-address" env:"STATSD_ADDRESS" description:"StatsD host:port metrics are sent to via UDP, disabled if empty."`
+	StatsdPrefix      string        `long:"statsd-prefix" default:"statuspage." env:"STATSD_PREFIX" required:"false" description:"Prefix of all StatsD metric names."`
+	StatsdFlush       time.Duration `long:"statsd-flush" default:"10s" env:"STATSD_FLUSH" required:"false" description:"Interval metrics are flushed to StatsD."`
+	AdminToken        string        `long:"admin-token" env:"ADMIN_TOKEN" secret:"true" description:"Bearer token protecting the admin and debug endpoints, they are rejected if empty."`
+	DebugEndpoints    bool          `long:"debug-endpoints" env:"DEBUG_ENDPOINTS" description:"Register the /debug endpoints."`
+	OutboundHeaders   []string      `long:"outbound-header" env:"OUTBOUND_HEADERS" env-delim:"," secret:"true" description:"Extra header key=value added to all outbound GitHub and Jenkins requests, can be repeated."`
+	IssuesUrl         string        `long:"issues-url" default:"https://github.com/dcos-terraform/statuspage/issues" env:"ISSUES_URL" required:"false" description:"Link to report an issue shown in the page footer, empty hides it."`
+	CallbackUrl       string        `long:"status-callback-url" env:"STATUS_CALLBACK_URL" description:"URL the summary and the recent transitions are posted to after every refresh."`
+	CallbackSecret    string        `long:"status-callback-secret" env:"STATUS_CALLBACK_SECRET" secret:"true" description:"Secret of the HMAC-SHA256 signature in the X-Statuspage-Signature header of the callbacks."`
+	CallbackRetries   int           `long:"status-callback-retries" default:"3" env:"STATUS_CALLBACK_RETRIES" required:"false" description:"Retries of a failed status callback with exponential backoff."`
+	StatsInFooter     bool          `long:"stats-in-footer" env:"STATS_IN_FOOTER" description:"Show the start time and a link to /stats in the page footer."`
+	HidePrivate       bool          `long:"hide-private-in-output" env:"HIDE_PRIVATE_IN_OUTPUT" description:"Leave private repos out of the page, the APIs only include them for requests with the admin token."`
+	RobotsAllow       bool          `long:"robots-allow" env:"ROBOTS_ALLOW" description:"Allow search engines to index the page in /robots.txt."`
+	PrintRoutes       bool          `long:"print-routes" description:"Print the registered routes and exit."`
 	Verbose           int           `short:"v" long:"verbose" env:"VERBOSE" description:"Be verbose."`
 }
 
+// Options is the parse target, the options in effect are published by
+// configure and /admin/reload as immutable snapshot.
+var Options options
+
+// optionsSnapshot holds the *options in effect. It is replaced as a whole on
+// reload, readers load it once per operation.
+var optionsSnapshot atomic.Value
+
+// currentOptions returns the options in effect, they must not be modified
+func currentOptions() *options {
+	return optionsSnapshot.Load().(*options)
+}
+
 const (
 	STATIC_DIR      = "/static/"
 	STATIC_CSS_FILE = "bootstrap.min.css"
+	PAGE_TITLE      = "DC/OS Terraform modules"
+	SOURCE_URL      = "https://github.com/dcos-terraform/statuspage"
 	GENERATOR       = `  <meta name="GENERATOR" content="dcos-terraform-statuspage`
 	HEAD_EXTRA      = `  <link rel="apple-touch-icon" sizes="180x180" href="/apple-touch-icon.png">
   <link rel="icon" type="image/png" sizes="32x32" href="/favicon-32x32.png">
@@ -50,6 +143,58 @@ const (
   <meta name="theme-color" content="#ffffff">`
 )
 
+// version and commit of the build, set by -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// badgeStates maps Badge.Result to the state name
+var badgeStates = []string{"notrun", "passing", "running", "failing", "aborted", "unstable"}
+
+// badgeStyle describes a generated badge, the width of the message part
+// matches the static SVGs.
+type badgeStyle struct {
+	Message string
+	Width   float64
+	Color   string
+}
+
+// badgeStyles is indexed by Badge.Result like badgeStates
+var badgeStyles = []*badgeStyle{
+	{Message: "not run", Width: 61, Color: "#9f9f9f"},
+	{Message: "passing", Width: 63, Color: "#44cc11"},
+	{Message: "running", Width: 67, Color: "#007ec6"},
+	{Message: "failing", Width: 54, Color: "#e05d44"},
+	{Message: "aborted", Width: 64, Color: "#9f9f9f"},
+	{Message: "unstable", Width: 67, Color: "#dfb317"},
+}
+
+const BADGE_SVG = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%[1]g" height="20">
+    <linearGradient id="a" x2="0" y2="100%%">
+        <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+        <stop offset="1" stop-opacity=".1"/>
+    </linearGradient>
+    <rect rx="3" width="%[1]g" height="20" fill="#555"/>
+    <rect rx="0" x="47.0" width="4" height="20" fill="%[2]s"/>
+    <rect rx="3" x="47.0" width="%[3]g" height="20" fill="%[2]s"/>
+    <rect rx="3" width="%[1]g" height="20" fill="url(#a)"/>
+    <g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">
+        <text x="24.5" y="15" fill="#010101" fill-opacity=".3">build</text>
+        <text x="24.5" y="14">build</text>
+        <text x="%[4]g" y="15" fill="#010101" fill-opacity=".3">%[5]s</text>
+        <text x="%[4]g" y="14">%[5]s</text>
+    </g>
+</svg>
+`
+
+var colorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// dynamicBadges is set if any badge color is configured, badges are then
+// generated by /badge/{state}.svg instead of using the static SVGs.
+var dynamicBadges bool
+
 type Badge struct {
 	Result int
 	Image  string
@@ -59,93 +204,257 @@ type CiResult struct {
 	BranchesIndex           int
 	BranchHtmlDoubleEncoded string
 	Build                   *Badge
+	// BuildNumber of the shown build, 0 if unknown
+	BuildNumber int
+	// Failed is set if the status couldn't be fetched, Stale if Build is
+	// the last known state of a previous refresh instead.
+	Failed bool
+	Stale  bool
+}
+
+// RepoInfo is the entry of a tracked repository exposed by /api/repos.
+type RepoInfo struct {
+	Name          string `json:"name"`
+	Provider      string `json:"provider"`
+	DefaultBranch string `json:"default_branch"`
+	Archived      bool   `json:"archived"`
+	URL           string `json:"url"`
 }
 
+// RepoStatus is the CI status of a tracked repository exposed by /api/status.
+type RepoStatus struct {
+	Name     string         `json:"name"`
+	Provider string         `json:"provider"`
+	URL      string         `json:"url"`
+	State    string         `json:"state"`
+	Branches []BranchStatus `json:"branches"`
+}
+
+type BranchStatus struct {
+	Branch string `json:"branch"`
+	State  string `json:"state"`
+	Result int    `json:"result"`
+	Stale  bool   `json:"stale,omitempty"`
+	Build  int    `json:"build,omitempty"`
+	Link   string `json:"link"`
+}
+
+// dataMutex guards repos, ciStatus, openPrs and the markdown caches against
+// the refresh goroutines.
+var dataMutex sync.RWMutex
 var markdownCache []byte
+var markdownProviderCache map[string][]byte
+
+// markdownVersion is increased whenever the markdown changes, it is part of
+// the renderCache keys so views rendered from older markdown are never hit.
+var markdownVersion int
+var renderCache *lru.Cache
+
+// renderGroup deduplicates concurrent renders of the same renderCache key
+var renderGroup singleflight.Group
+
+var outboundHeaders http.Header
+var httpClient *http.Client
+
+// jenkinsClient is used for all Jenkins requests, it doesn't follow redirects
+// with --jenkins-no-redirect.
+var jenkinsClient *http.Client
+
+// callbackClient doesn't send the outbound headers, they are meant for GitHub
+// and Jenkins
+var callbackClient = &http.Client{Timeout: 30 * time.Second}
+var statsd *statsdClient
 var provider []string
 var branches []string
 var repos map[string][]*github.Repository
-var ciStatus []CiResult
+var ciStatus map[string][]CiResult
+var openPrs map[string]int
+var providerCiRefresh map[string]time.Duration
 
-func main() {
-	ParseArgs(&Options)
-	provider = append(provider, []string{"aws", "azurerm", "gcp", "null", "template"}...)
-	branches = append(branches, []string{"support/0.2.x", "support/0.1.x"}...)
-	repos = make(map[string][]*github.Repository, len(provider))
+// refreshGeneration is increased on every reload, refresh loops and CI
+// results of older generations are discarded. Guarded by dataMutex.
+var refreshGeneration int
 
-	r := mux.NewRouter()
-	r.HandleFunc("/", handler)
-	r.HandleFunc("/health", livenessHandler)
+// StatusChange is an entry of the event log of state transitions.
+type StatusChange struct {
+	Time   time.Time `json:"time"`
+	Repo   string    `json:"repo"`
+	Branch string    `json:"branch"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+}
 
-	files, err := ioutil.ReadDir(STATIC_DIR + "images/favicon")
-	CheckErrorFatal(err)
-	for _, file := range files {
-		r.HandleFunc("/"+file.Name(), faviconHandler)
-	}
-	r.PathPrefix(STATIC_DIR).Handler(http.StripPrefix(STATIC_DIR, http.FileServer(http.Dir(STATIC_DIR))))
+// statusChanges are the last --recent-changes transitions, oldest first.
+// Guarded by dataMutex.
+var statusChanges []StatusChange
+
+// pendingCallbackChanges are the transitions not yet posted to
+// --status-callback-url, at most maxPendingCallbackChanges, oldest first.
+// Guarded by dataMutex.
+var pendingCallbackChanges []StatusChange
+
+// maxPendingCallbackChanges bounds the transitions kept for an unreachable
+// callback receiver
+const maxPendingCallbackChanges = 1000
+
+// refreshCtx is cancelled by stopRefresh on shutdown, the refresh loops stop
+// and their upstream requests are aborted.
+var refreshCtx, stopRefresh = context.WithCancel(context.Background())
+
+// lastCiRefresh is the time CI results have been stored last. Guarded by
+// dataMutex.
+var lastCiRefresh time.Time
+
+// orgRefresh and ciRefresh are held while a GitHub org refresh respectively a
+// CI status refresh is running. They are shared by warmup, the refresh loops of
+// every generation and /admin/reload so refreshes never overlap, org is taken
+// before ci. inFlightRefreshes counts all running periodic refreshes.
+var orgRefresh = make(chan struct{}, 1)
+var ciRefresh = make(chan struct{}, 1)
+var inFlightRefreshes int32
+
+// assetsError holds the error message of the last failed assets check, empty
+// if the static files are readable.
+var assetsError atomic.Value
+
+// repoBadges are the informational labels shown next to the repo names
+var repoBadges map[string][]string
+
+// primaryBranch is the normalized --primary-branch
+var primaryBranch string
+
+// branchAliases maps branch names to the shorter names shown in the table
+// headers
+var branchAliases map[string]string
+
+// progressTotal and progressFetched count the repos of the initial refresh
+// for the loading page
+var progressTotal int32
+var progressFetched int32
+
+// ready is set once the initial refresh is done or the warmup timed out
+var ready int32
+
+// reloadableOptions can be changed at runtime by /admin/reload, changes to
+// all other options require a restart.
+var reloadableOptions = map[string]bool{
+	"Providers":         true,
+	"Branches":          true,
+	"GitHubRepoPrefix":  true,
+	"GitHubOrgRefresh":  true,
+	"CiStatusRefresh":   true,
+	"ProviderCiRefresh": true,
+	"MinRefresh":        true,
+	"RefreshJitter":     true,
+	"LatestBranchOnly":  true,
+	"UnknownProvider":   true,
+	"AlwaysInclude":     true,
+	"AlwaysSection":     true,
+	"GroupBy":           true,
+	"PruneEmptyColumns": true,
+	"Strict":            true,
+}
+
+// optionChange is reported by /admin/reload for every changed option
+type optionChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+func main() {
+	ParseArgs(&Options)
+	configure()
+	r := newRouter()
 	http.Handle("/", r)
 
 	walkErr := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
 		pathTemplate, _ := route.GetPathTemplate()
+		if Options.PrintRoutes {
+			fmt.Println(pathTemplate)
+		}
 		glog.Infof("Registered: %s", pathTemplate)
 		return nil
 	})
 	CheckErrorFatal(walkErr)
+	if Options.PrintRoutes {
+		os.Exit(0)
+	}
 
 	srv := &http.Server{
-		Handler:      handlers.ProxyHeaders(r),
-		Addr:         fmt.Sprintf(":%d", Options.Listen),
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Handler:        handlers.ProxyHeaders(r),
+		Addr:           fmt.Sprintf(":%d", Options.Listen),
+		WriteTimeout:   15 * time.Second,
+		ReadTimeout:    15 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: Options.MaxHeaderBytes,
 	}
 
-	done := make(chan bool)
 	go func() {
-		fetchRepositorys(Options.GitHubOrg)
-		markdownContent()
-		done <- true
-		for {
-			<-time.After(Options.GitHubOrgRefresh)
-			go fetchRepositorys(Options.GitHubOrg)
+		if glog.V(9) {
+			glog.Infof("Running initial fetchRepositorys(\"%s\"), fetchCiStatus() and markdownContent()", Options.GitHubOrg)
 		}
-	}()
-	go func() {
+		warmup(Options.WarmupTimeout)
 		for {
-			<-time.After(Options.CiStatusRefresh)
-			go markdownContent()
+			select {
+			case <-time.After(jittered(currentOptions().GitHubOrgRefresh)):
+			case <-refreshCtx.Done():
+				return
+			}
+			runExclusive(orgRefresh, "GitHub org", func() {
+				fetchRepositorys(currentOptions().GitHubOrg)
+			})
 		}
 	}()
-
-	if glog.V(9) {
-		glog.Infof("Waiting for initial fetchRepositorys(\"%s\") and markdownContent() to be done", Options.GitHubOrg)
+	startCiRefreshLoops()
+	if Options.AssetsCheck > 0 {
+		checkAssets()
+		go func() {
+			for range time.Tick(Options.AssetsCheck) {
+				checkAssets()
+			}
+		}()
 	}
 
-	<-done
-
+	// the status page is served with 503 until the initial refresh is done
 	glog.Infof("Start server on :%d", Options.Listen)
 	go func() {
 		srv.ListenAndServe()
 	}()
 
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigs, shutdownSignals...)
 
 	<-sigs
-	ctx, cancel := context.WithTimeout(context.Background(), Options.Timeout)
+	glog.Info("Signal received: stopping the refresh loops")
+	stopRefresh()
+	grace := Options.ShutdownGrace
+	if grace == 0 {
+		grace = Options.Timeout
+	}
+	glog.Infof("Waiting up to %s for in-flight requests to finish", grace)
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
 	defer cancel()
-	srv.Shutdown(ctx)
-	glog.Info("Signal received: now exiting")
+	if err := srv.Shutdown(ctx); err != nil {
+		glog.Warningf("Requests still in flight after %s: %v", grace, err)
+	}
+	glog.Info("Server stopped: now exiting")
 	os.Exit(0)
 }
 
+// fetchRepositorys assigns the repos of the org to the provider sections of
+// the package level repos map and returns all tracked repos.
 func fetchRepositorys(org string) []*github.Repository {
-	ctx := context.Background()
+	o := currentOptions()
+	start := time.Now()
+	ctx := context.WithValue(refreshCtx, oauth2.HTTPClient, httpClient)
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: Options.GitHubAccessToken},
+		&oauth2.Token{AccessToken: o.GitHubAccessToken},
 	)
 	tc := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(tc)
+	baseUrl, err := url.Parse(strings.TrimSuffix(o.GitHubApiUrl, "/") + "/")
+	CheckErrorFatal(err)
+	client.BaseURL = baseUrl
 
 	opt := &github.RepositoryListByOrgOptions{
 		ListOptions: github.ListOptions{PerPage: 10},
@@ -153,201 +462,2440 @@ func fetchRepositorys(org string) []*github.Repository {
 
 	var allRepos []*github.Repository
 	for {
-		repos, resp, err := client.Repositories.ListByOrg(ctx, org, opt)
+		// page must not shadow the package level repos
+		page, resp, err := client.Repositories.ListByOrg(ctx, org, opt)
+		statsd.incr("github.requests", 1)
+		if refreshCtx.Err() != nil {
+			// shutting down
+			return nil
+		}
 		CheckErrorFatal(err)
-		allRepos = append(allRepos, repos...)
+		allRepos = append(allRepos, page...)
 		if resp.NextPage == 0 {
 			break
 		}
 		opt.Page = resp.NextPage
 	}
 
+	dataMutex.Lock()
+	tracked := 0
+	matched := make(map[string]bool)
 	for _, i := range provider {
 		repos[i] = nil
+	}
+	for _, i := range provider {
+		// skip the catch-all and always include sections
+		if !contains(o.Providers, i) {
+			continue
+		}
 		for _, repo := range allRepos {
 			// Non archived repos only
 			if *repo.Archived != true {
 				// Only repos matching our current module patterns
-				r, _ := regexp.Compile("^(" + Options.GitHubRepoPrefix + ")(" + i + ").*$")
+				r, _ := regexp.Compile("^(" + o.GitHubRepoPrefix + ")(" + i + ").*$")
 				if r.MatchString(*repo.Name) {
 					repos[i] = append(repos[i], repo)
+					matched[*repo.Name] = true
 				}
 			}
 		}
+		tracked += len(repos[i])
+	}
+	for _, name := range o.AlwaysInclude {
+		found := false
+		for _, repo := range allRepos {
+			if *repo.Name != name {
+				continue
+			}
+			found = true
+			if *repo.Archived != true && !matched[*repo.Name] {
+				repos[o.AlwaysSection] = append(repos[o.AlwaysSection], repo)
+				matched[*repo.Name] = true
+				tracked++
+			}
+		}
+		if !found {
+			glog.Warningf("Repo %s to always include not found in %s", name, org)
+		}
+	}
+	if o.UnknownProvider != "" {
+		// Module repos of providers not configured are collected in a
+		// catch-all section instead of being dropped
+		r, _ := regexp.Compile("^(" + o.GitHubRepoPrefix + ")[^-]+-.*$")
+		for _, repo := range allRepos {
+			if *repo.Archived != true && !matched[*repo.Name] && r.MatchString(*repo.Name) {
+				repos[o.UnknownProvider] = append(repos[o.UnknownProvider], repo)
+			}
+		}
+		tracked += len(repos[o.UnknownProvider])
+	}
+	var trackedRepos []*github.Repository
+	for _, p := range provider {
+		trackedRepos = append(trackedRepos, repos[p]...)
 	}
+	showOpenPrs := o.ShowOpenPrs
+	dataMutex.Unlock()
+	statsd.gauge("repos", int64(tracked))
+	atomic.StoreInt32(&progressTotal, int32(len(trackedRepos)))
 
-	return nil
+	if showOpenPrs {
+		fetchOpenPullRequests(ctx, client, org, trackedRepos)
+	}
+	statsd.timing("github.fetch", time.Since(start))
+	githubRefreshStats.record(time.Since(start), false)
+
+	return trackedRepos
+}
+
+// fetchOpenPullRequests counts the open pull requests of the repositories and
+// stores them in openPrs. Repositories failing to report are stored as -1.
+func fetchOpenPullRequests(ctx context.Context, client *github.Client, org string, repoList []*github.Repository) {
+	counts := make(map[string]int, len(repoList))
+	for _, repo := range repoList {
+		opt := &github.PullRequestListOptions{
+			State:       "open",
+			ListOptions: github.ListOptions{PerPage: 1},
+		}
+		prs, resp, err := client.PullRequests.List(ctx, org, *repo.Name, opt)
+		statsd.incr("github.requests", 1)
+		if err != nil {
+			glog.Warningf("Failed to fetch open pull requests of %s: %v", *repo.Name, err)
+			counts[*repo.Name] = -1
+			continue
+		}
+		// one pull request per page, the last page is the count
+		if resp.LastPage > 0 {
+			counts[*repo.Name] = resp.LastPage
+		} else {
+			counts[*repo.Name] = len(prs)
+		}
+	}
+
+	dataMutex.Lock()
+	openPrs = counts
+	dataMutex.Unlock()
 }
 
-func getJenkinsBuildStatusBadge(repoName string) []CiResult {
-	done := make(chan bool)
-	returnCiRes := make([]CiResult, 0)
+func getJenkinsBuildStatusBadge(repoName string, branches []string) []CiResult {
+	returnCiRes := make([]CiResult, len(branches))
 	if glog.V(9) {
 		glog.Infof("Repo to check: %s", repoName)
 	}
-	sliceSize := len(branches)
+	fetch := fetchJenkinsTextStatus
+	switch currentOptions().JenkinsMode {
+	case "json":
+		fetch = fetchJenkinsJsonStatus
+	case "multibranch":
+		jobs, err := fetchJenkinsMultibranchJobs(repoName)
+		switch err.(type) {
+		case nil:
+			fetch = func(repoName string, branch string, branchHtmlDoubleEncoded string) (int, int, error) {
+				build, ok := jobs[branch]
+				if !ok || build == nil {
+					// no job or no build of the branch yet
+					return 0, 0, nil
+				}
+				return jenkinsBuildResult(*build), build.Number, nil
+			}
+		case jenkinsParseError:
+			glog.Warningf("Failed to parse the branch jobs of \"%s\", querying every branch: %v", repoName, err)
+			fetch = fetchJenkinsJsonStatus
+		default:
+			fetch = func(repoName string, branch string, branchHtmlDoubleEncoded string) (int, int, error) {
+				return 0, 0, err
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
 	for i, branch := range branches {
+		wg.Add(1)
 		go func(i int, b string) {
+			defer wg.Done()
 			branchHtmlDoubleEncoded := url.QueryEscape(url.QueryEscape(b))
-			res, err := http.Get("https://jenkins-terraform.mesosphere.com/service/dcos-terraform-jenkins/buildStatus/text?job=dcos-terraform%2F" + repoName + "%2F" + branchHtmlDoubleEncoded)
-			CheckErrorFatal(err)
-			body, err := ioutil.ReadAll(res.Body)
-			res.Body.Close()
-			CheckErrorFatal(err)
-			if glog.V(9) {
-				glog.Infof("Result jenkins request for \"%s\" in branch \"%s\": %s", repoName, b, string(body))
-			}
+			result, number, err := fetch(repoName, b, branchHtmlDoubleEncoded)
 
-			cires := new(CiResult)
 			badge := new(Badge)
-			if res.StatusCode != http.StatusOK {
-				badge.Image = STATIC_DIR + "images/0-build-notrun.svg"
-				badge.Result = 0
-			}
-
-			switch true {
-			case string(body) == "Success":
-				badge.Image = STATIC_DIR + "images/1-build-passing.svg"
-				badge.Result = 1
-			case string(body) == "In progress":
-				badge.Image = STATIC_DIR + "images/2-build-running.svg"
-				badge.Result = 2
-			case string(body) == "Failed":
-				badge.Image = STATIC_DIR + "images/3-build-failing.svg"
-				badge.Result = 3
-			case string(body) == "Aborted":
-				badge.Image = STATIC_DIR + "images/4-build-aborted.svg"
-				badge.Result = 4
-			default:
-				badge.Image = STATIC_DIR + "images/0-build-notrun.svg"
-				badge.Result = 0
+			badge.Result = result
+			badge.Image = badgeImage(badge.Result)
+			cires := CiResult{
+				BranchesIndex:           i,
+				BranchHtmlDoubleEncoded: branchHtmlDoubleEncoded,
+				Build:                   badge,
+				BuildNumber:             number,
 			}
-
-			cires.BranchesIndex = i
-			cires.BranchHtmlDoubleEncoded = branchHtmlDoubleEncoded
-			cires.Build = badge
-			returnCiRes = append(returnCiRes, *cires)
-			if len(returnCiRes) == sliceSize {
-				done <- true
+			if err != nil {
+				statsd.incr("jenkins.errors", 1)
+				glog.Warningf("Failed to fetch build status of \"%s\" in branch \"%s\": %v", repoName, b, err)
+				cires.Failed = true
 			}
+			returnCiRes[i] = cires
 		}(i, branch)
 	}
 
-	<-done
+	wg.Wait()
 	return returnCiRes
 }
 
-func markdownContent() []byte {
-	if glog.V(5) {
-		for _, p := range provider {
-			glog.Infof("Repositories "+p+": %d", len(repos[p]))
-		}
+// jenkinsGet requests an url of Jenkins, the request is cancelled on shutdown.
+func jenkinsGet(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
 	}
+	start := time.Now()
+	res, err := jenkinsClient.Do(req.WithContext(refreshCtx))
+	statsd.incr("jenkins.requests", 1)
+	statsd.timing("jenkins.request", time.Since(start))
+	return res, err
+}
 
-	var md []byte
-	separator := []byte("---\n")
-	topic := []byte("# DC/OS Terraform modules\n")
-	md = append(md, topic...)
+// fetchJenkinsTextStatus maps the buildStatus/text response of a repository
+// branch to a Badge.Result, the text doesn't contain a build number.
+func fetchJenkinsTextStatus(repoName string, branch string, branchHtmlDoubleEncoded string) (int, int, error) {
+	res, err := jenkinsGet(jenkinsBuildStatusUrl(repoName, branchHtmlDoubleEncoded))
+	if err != nil {
+		return 0, 0, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return 0, 0, err
+	}
+	if glog.V(9) {
+		glog.Infof("Result jenkins request for \"%s\" in branch \"%s\": %s", repoName, branch, string(body))
+	}
+	if res.StatusCode == http.StatusNotFound {
+		// a branch without job is not run, not a failed request
+		return 0, 0, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("Jenkins responded %s", res.Status)
+	}
 
-	for _, p := range provider {
-		md = append(md, separator...)
-		providers := []byte("### Provider: **" + p + "**\n")
-		tablehead := []byte("| Repository | support/0.2.x | support/0.1.x |\n")
-		tablesplit := []byte("| --- | --- | --- |\n")
-		md = append(md, providers...)
-		md = append(md, tablehead...)
-		md = append(md, tablesplit...)
+	switch true {
+	case string(body) == "Success":
+		return 1, 0, nil
+	case string(body) == "In progress":
+		return 2, 0, nil
+	case string(body) == "Failed":
+		return 3, 0, nil
+	case string(body) == "Aborted":
+		return 4, 0, nil
+	case string(body) == "Unstable" && currentOptions().Unstable:
+		return 5, 0, nil
+	default:
+		return 0, 0, nil
+	}
+}
 
-		status_badge_icon_prefix := "[![Build Status]("
-		status_badge_link_prefix := "(https://jenkins-terraform.mesosphere.com/service/dcos-terraform-jenkins/job/dcos-terraform/job/"
+// jenkinsBuild is the part of lastBuild/api/json being used, Result is null
+// while the build is running.
+type jenkinsBuild struct {
+	Result   *string `json:"result"`
+	Building bool    `json:"building"`
+	Number   int     `json:"number"`
+}
 
-		for _, repo := range repos[p] {
-			md = append(md, "| "+*repo.Name+" | "+status_badge_icon_prefix...)
+// fetchJenkinsJsonStatus maps the result of the last build of a repository
+// branch to a Badge.Result and returns the build number.
+func fetchJenkinsJsonStatus(repoName string, branch string, branchHtmlDoubleEncoded string) (int, int, error) {
+	res, err := jenkinsGet(jenkinsLastBuildUrl(repoName, branchHtmlDoubleEncoded))
+	if err != nil {
+		return 0, 0, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return 0, 0, err
+	}
+	if glog.V(9) {
+		glog.Infof("Result jenkins request for \"%s\" in branch \"%s\": %s", repoName, branch, string(body))
+	}
+	if res.StatusCode == http.StatusNotFound {
+		// a branch without job is not run, not a failed request
+		return 0, 0, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("Jenkins responded %s", res.Status)
+	}
+	var build jenkinsBuild
+	if err := json.Unmarshal(body, &build); err != nil {
+		return 0, 0, err
+	}
+	return jenkinsBuildResult(build), build.Number, nil
+}
 
-			badges := getJenkinsBuildStatusBadge(*repo.Name)
-			// sort
-			sort.SliceStable(badges, func(i, j int) bool {
-				return badges[i].BranchesIndex < badges[j].BranchesIndex
-			})
+// jenkinsBuildResult maps the result of a build to a Badge.Result
+func jenkinsBuildResult(build jenkinsBuild) int {
+	switch true {
+	case build.Building || build.Result == nil:
+		return 2
+	case *build.Result == "SUCCESS":
+		return 1
+	case *build.Result == "FAILURE":
+		return 3
+	case *build.Result == "ABORTED":
+		return 4
+	case *build.Result == "UNSTABLE" && currentOptions().Unstable:
+		return 5
+	default:
+		return 0
+	}
+}
 
-			lastBadge := len(badges) - 1
-			for i, badge := range badges {
-				if glog.V(9) {
-					glog.Infof("Branch \"%s\" gets \"%s\"", branches[badge.BranchesIndex], badge.Build.Image)
-				}
-				if i == lastBadge {
-					md = append(md, badge.Build.Image+")]"+status_badge_link_prefix+*repo.Name+"/job/"+badge.BranchHtmlDoubleEncoded+"/) "...)
-				} else {
-					md = append(md, badge.Build.Image+")]"+status_badge_link_prefix+*repo.Name+"/job/"+badge.BranchHtmlDoubleEncoded+"/) | "+status_badge_icon_prefix...)
-				}
-			}
-			md = append(md, "|\n"...)
+// jenkinsParseError is returned for unexpected responses of the multibranch
+// job API.
+type jenkinsParseError struct {
+	error
+}
+
+// fetchJenkinsMultibranchJobs returns the last build of all branch jobs of a
+// repository with a single request, keyed by branch name. The last build is
+// nil if a branch wasn't built yet.
+func fetchJenkinsMultibranchJobs(repoName string) (map[string]*jenkinsBuild, error) {
+	res, err := jenkinsGet(jenkinsMultibranchUrl(repoName))
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if glog.V(9) {
+		glog.Infof("Result jenkins request for \"%s\": %s", repoName, string(body))
+	}
+	if res.StatusCode == http.StatusNotFound {
+		// the repo has no job, all branches are not run
+		return map[string]*jenkinsBuild{}, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Jenkins responded %s", res.Status)
+	}
+	var job struct {
+		Jobs *[]struct {
+			Name      string        `json:"name"`
+			LastBuild *jenkinsBuild `json:"lastBuild"`
+		} `json:"jobs"`
+	}
+	if err := json.Unmarshal(body, &job); err != nil {
+		return nil, jenkinsParseError{err}
+	}
+	if job.Jobs == nil {
+		return nil, jenkinsParseError{fmt.Errorf("not a multibranch job")}
+	}
+	builds := make(map[string]*jenkinsBuild, len(*job.Jobs))
+	for _, j := range *job.Jobs {
+		// the job names are the encoded branch names
+		name, err := url.PathUnescape(j.Name)
+		if err != nil {
+			return nil, jenkinsParseError{err}
 		}
+		builds[name] = j.LastBuild
 	}
-	markdownCache = md
-	return nil
+	return builds, nil
 }
 
-func renderMarkdownHtml() string {
-	flags := html.CommonFlags | html.CompletePage | html.HrefTargetBlank
-	opts := html.RendererOptions{
-		Title:     "DC/OS Terraform modules",
-		Flags:     flags,
-		CSS:       STATIC_DIR + "css/" + STATIC_CSS_FILE,
-		Icon:      "/favicon.ico",
-		Head:      []byte(HEAD_EXTRA),
-		Generator: GENERATOR,
+// configure sets up the package state from the parsed Options
+func configure() {
+	published := Options
+	optionsSnapshot.Store(&published)
+	outboundHeaders = parseOutboundHeaders(Options.OutboundHeaders)
+	httpClient = &http.Client{Transport: &headerTransport{header: outboundHeaders, base: http.DefaultTransport}}
+	jenkinsClient = &http.Client{Transport: httpClient.Transport}
+	if Options.ShowBuildNumber && Options.JenkinsMode == "text" {
+		glog.Warning("--show-build-number requires --jenkins-mode=json or multibranch, no build numbers are shown")
+	}
+	if Options.CallbackUrl != "" && Options.CiBackend == "actions-badge" {
+		glog.Warning("--status-callback-url requires --ci-backend=jenkins, no status callbacks are posted")
+	}
+	if Options.JenkinsNoRedirect {
+		// e.g. a login page of an auth proxy must not be parsed as status,
+		// the 3xx response is treated as failed request
+		jenkinsClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	var err error
+	provider, err = resolveProviders(Options.Providers, Options.UnknownProvider, alwaysIncludeSection(Options.AlwaysInclude, Options.AlwaysSection))
+	if err != nil {
+		ErrorPrintHelpAndExit(&Options, err.Error())
+	}
+	branches, err = resolveBranches(Options.Branches, Options.LatestBranchOnly)
+	if err != nil {
+		ErrorPrintHelpAndExit(&Options, err.Error())
+	}
+	configured, _ := resolveBranches(Options.Branches, false)
+	branchAliases = parseBranchAliases(Options.BranchAliases, configured)
+	repoBadges = parseRepoBadges(Options.RepoBadges)
+	pageHead = HEAD_EXTRA
+	primaryBranch = strings.TrimPrefix(strings.TrimSpace(Options.PrimaryBranch), "refs/heads/")
+	if err := checkPrimaryBranch(Options.Branches); err != nil {
+		ErrorPrintHelpAndExit(&Options, err.Error())
+	}
+	// the emphasized column of the primary branch gets more room
+	pageHead += "\n  <style>.primary-branch { display: inline-block; min-width: 10em; }</style>"
+	for _, css := range Options.ExtraCss {
+		href, err := staticPath(css)
+		if err != nil {
+			ErrorPrintHelpAndExit(&Options, fmt.Sprintf("Invalid extra CSS \"%s\": %v", css, err))
+		}
+		pageHead += "\n  <link rel=\"stylesheet\" type=\"text/css\" href=\"" + nethtml.EscapeString(href) + "\">"
+	}
+	configureBadgeColors()
+	repos = make(map[string][]*github.Repository, len(provider))
+	ciStatus = make(map[string][]CiResult)
+	providerCiRefresh, err = parseProviderCiRefresh(Options.ProviderCiRefresh, provider, Options.MinRefresh)
+	if err != nil {
+		ErrorPrintHelpAndExit(&Options, err.Error())
+	}
+	if Options.RecentChanges < 0 {
+		ErrorPrintHelpAndExit(&Options, "The number of recent changes must not be negative")
+	}
+	if Options.RenderCacheSize < 1 {
+		ErrorPrintHelpAndExit(&Options, "The render cache size must be at least 1")
+	}
+	renderCache, err = lru.New(Options.RenderCacheSize)
+	CheckErrorFatal(err)
+	if Options.StatsdAddress != "" {
+		statsd = newStatsdClient(Options.StatsdAddress, Options.StatsdPrefix)
+		go statsd.flushLoop(Options.StatsdFlush)
 	}
-	renderer := html.NewRenderer(opts)
-	return string(markdown.ToHTML(markdownCache, nil, renderer))
 }
 
-func handler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Cache-Control", "max-age=600")
-	fmt.Fprint(w, renderMarkdownHtml())
+// newRouter registers all handlers
+func newRouter() *mux.Router {
+	o := currentOptions()
+	r := mux.NewRouter()
+	r.HandleFunc("/", handler)
+	r.HandleFunc("/health", livenessHandler)
+	r.HandleFunc("/ready", readinessHandler)
+	if o.AssetsCheck > 0 {
+		r.HandleFunc("/health/assets", assetsHealthHandler)
+	}
+	r.HandleFunc("/api/repos", reposApiHandler)
+	r.HandleFunc("/embed", embedHandler)
+	r.HandleFunc("/api/status", statusApiHandler)
+	r.HandleFunc("/api/summary", summaryApiHandler)
+	r.HandleFunc("/stats", statsHandler)
+	r.HandleFunc("/raw", rawHandler)
+	r.HandleFunc("/status.csv", csvHandler)
+	r.HandleFunc("/badge/{state:[a-z]+}.svg", badgeHandler)
+	r.HandleFunc("/robots.txt", robotsHandler)
+	r.HandleFunc("/admin/reload", requireAdmin(limitBody(reloadHandler))).Methods("POST")
+	if o.DebugEndpoints {
+		r.HandleFunc("/debug/jenkins/{repo}/{branch:.+}", requireAdmin(debugJenkinsHandler))
+		r.HandleFunc("/debug/config", requireAdmin(debugConfigHandler))
+	}
+
+	files, err := ioutil.ReadDir(STATIC_DIR + "images/favicon")
+	CheckErrorFatal(err)
+	for _, file := range files {
+		r.HandleFunc("/"+file.Name(), faviconHandler)
+	}
+	r.PathPrefix(STATIC_DIR).Handler(http.StripPrefix(STATIC_DIR, http.FileServer(http.Dir(STATIC_DIR))))
+	return r
 }
 
-func livenessHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
+// refreshAll fetches the repositories and the CI status of all providers and
+// regenerates the markdown afterwards. It waits for running refreshes instead
+// of overlapping them.
+func refreshAll() {
+	orgRefresh <- struct{}{}
+	fetchRepositorys(currentOptions().GitHubOrg)
+	ciRefresh <- struct{}{}
+	<-orgRefresh
+	dataMutex.RLock()
+	providers := provider
+	dataMutex.RUnlock()
+	fetchCiStatus(providers)
+	markdownContent()
+	<-ciRefresh
+	go postStatusCallback()
 }
 
-func faviconHandler(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, STATIC_DIR+"images/favicon"+r.URL.Path)
+// warmup runs the initial refresh and flips ready once the repositories and
+// the CI status are fetched. If the CI scrape exceeds the timeout the page is
+// rendered with the status fetched so far and ready flips anyway, the scrape
+// goes on in the background.
+func warmup(timeout time.Duration) {
+	orgRefresh <- struct{}{}
+	fetchRepositorys(currentOptions().GitHubOrg)
+	ciRefresh <- struct{}{}
+	<-orgRefresh
+	dataMutex.RLock()
+	providers := provider
+	dataMutex.RUnlock()
+
+	scraped := make(chan bool)
+	go func() {
+		fetchCiStatus(providers)
+		markdownContent()
+		close(scraped)
+		<-ciRefresh
+		postStatusCallback()
+	}()
+
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timedOut = time.After(timeout)
+	}
+	select {
+	case <-scraped:
+		glog.Info("Ready: repositories and initial CI status fetched")
+	case <-timedOut:
+		markdownContent()
+		glog.Warningf("Ready: initial CI scrape exceeded the warmup timeout of %s, showing not run badges until it is done", timeout)
+	}
+	atomic.StoreInt32(&ready, 1)
 }
 
-// ParseArgs needs a struct compatible to jeddevdk/go-flags and will fill it
-// based on CLI parameters.
-func ParseArgs(options interface{}) {
-	_, err := flags.ParseArgs(options, os.Args)
-	if err != nil {
-		if err.(*flags.Error).Type == flags.ErrHelp {
-			os.Exit(0)
-		} else {
-			panic(err)
+// startCiRefreshLoops starts one refresh loop for all providers using the
+// default interval and one for every provider with its own interval.
+func startCiRefreshLoops() {
+	dataMutex.RLock()
+	generation := refreshGeneration
+	var defaultRefreshProvider []string
+	for _, p := range provider {
+		if _, ok := providerCiRefresh[p]; !ok {
+			defaultRefreshProvider = append(defaultRefreshProvider, p)
+		}
+	}
+	interval := currentOptions().CiStatusRefresh
+	overrides := make(map[string]time.Duration, len(providerCiRefresh))
+	for p, d := range providerCiRefresh {
+		overrides[p] = d
+	}
+	dataMutex.RUnlock()
+
+	go refreshCiStatusLoop(defaultRefreshProvider, interval, generation)
+	for p, d := range overrides {
+		go refreshCiStatusLoop([]string{p}, d, generation)
+	}
+}
+
+// refreshCiStatusLoop periodically fetches the CI status of the given
+// providers and regenerates the markdown afterwards. It stops once the
+// configuration got reloaded.
+func refreshCiStatusLoop(providers []string, interval time.Duration, generation int) {
+	if len(providers) == 0 {
+		return
+	}
+	for {
+		select {
+		case <-time.After(jittered(interval)):
+		case <-refreshCtx.Done():
+			return
 		}
+		dataMutex.RLock()
+		current := refreshGeneration
+		dataMutex.RUnlock()
+		if current != generation {
+			return
+		}
+		runExclusive(ciRefresh, "CI status "+strings.Join(providers, ","), func() {
+			fetchCiStatus(providers)
+			markdownContent()
+			// a slow receiver must not hold the guard
+			go postStatusCallback()
+		})
 	}
+}
 
-	fixGlog(options)
+// jitterRand is seeded per process so instances don't jitter in lockstep,
+// guarded by jitterMutex
+var jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+var jitterMutex sync.Mutex
+
+// jittered randomizes a refresh interval by up to +-RefreshJitter, it doesn't
+// drop below MinRefresh.
+func jittered(interval time.Duration) time.Duration {
+	o := currentOptions()
+	jitter := o.RefreshJitter
+	if jitter <= 0 {
+		return interval
+	}
+	jitterMutex.Lock()
+	offset := time.Duration(jitterRand.Int63n(int64(2*jitter)+1)) - jitter
+	jitterMutex.Unlock()
+	if interval+offset < o.MinRefresh {
+		return o.MinRefresh
+	}
+	return interval + offset
 }
 
-// ErrorPrintHelpAndExit prints the message, the help message and exits
-func ErrorPrintHelpAndExit(options interface{}, message string) {
-	fmt.Fprintln(os.Stderr, message+"\n")
-	var parser = flags.NewParser(options, flags.Default)
-	parser.WriteHelp(os.Stderr)
-	os.Exit(1)
+// runExclusive runs f in the background unless another refresh holding guard
+// is still in progress. Skipped runs are counted.
+func runExclusive(guard chan struct{}, name string, f func()) {
+	select {
+	case guard <- struct{}{}:
+	default:
+		glog.Warningf("Skipping %s refresh, the previous one is still running", name)
+		statsd.incr("refresh.skipped", 1)
+		return
+	}
+	statsd.gauge("refresh.inflight", int64(atomic.AddInt32(&inFlightRefreshes, 1)))
+	go func() {
+		defer func() { <-guard }()
+		defer func() {
+			statsd.gauge("refresh.inflight", int64(atomic.AddInt32(&inFlightRefreshes, -1)))
+		}()
+		f()
+	}()
 }
 
-// configure glog, not used for flag parsing
-func fixGlog(options interface{}) {
-	flag.Set("logtostderr", "true")
+// fetchCiStatus queries Jenkins for all repositories of the given providers
+// and stores the results in ciStatus.
+func fetchCiStatus(providers []string) {
+	o := currentOptions()
+	if o.CiBackend == "actions-badge" {
+		return
+	}
+	start := time.Now()
+	failed := false
+	defer func() {
+		statsd.timing("ci.refresh", time.Since(start))
+		ciRefreshStats.record(time.Since(start), failed)
+	}()
 
-	verbose := reflect.ValueOf(options).Elem().FieldByName("Verbose")
-	if verbose.IsValid() {
-		flag.Set("v", strconv.Itoa(verbose.Interface().(int)))
+	dataMutex.RLock()
+	var providerRepos []*github.Repository
+	for _, p := range providers {
+		providerRepos = append(providerRepos, repos[p]...)
+	}
+	branchList := branches
+	generation := refreshGeneration
+	dataMutex.RUnlock()
+
+	for _, repo := range providerRepos {
+		if refreshCtx.Err() != nil {
+			return
+		}
+		badges := getJenkinsBuildStatusBadge(*repo.Name, branchList)
+		for _, badge := range badges {
+			failed = failed || badge.Failed
+		}
+		if atomic.LoadInt32(&ready) == 0 {
+			atomic.AddInt32(&progressFetched, 1)
+		}
+		// sort
+		sort.SliceStable(badges, func(i, j int) bool {
+			return badges[i].BranchesIndex < badges[j].BranchesIndex
+		})
+
+		dataMutex.Lock()
+		if generation != refreshGeneration {
+			// branches might have changed, the result indexes are invalid
+			dataMutex.Unlock()
+			return
+		}
+		if o.StickyStatus {
+			keepLastKnownStatus(badges, ciStatus[*repo.Name])
+		}
+		recordTransitions(*repo.Name, branchList, ciStatus[*repo.Name], badges)
+		ciStatus[*repo.Name] = badges
+		lastCiRefresh = time.Now()
+		dataMutex.Unlock()
+	}
+}
+
+// recordTransitions adds a StatusChange to the event log for every branch
+// with a different state than in the previous results. Must be called with
+// dataMutex held.
+func recordTransitions(repoName string, branchList []string, previous []CiResult, results []CiResult) {
+	o := currentOptions()
+	for _, res := range results {
+		if res.Failed && !res.Stale {
+			// not a state of the build
+			continue
+		}
+		for _, prev := range previous {
+			if prev.BranchesIndex != res.BranchesIndex || prev.Build.Result == res.Build.Result || (prev.Failed && !prev.Stale) {
+				continue
+			}
+			change := StatusChange{
+				Time:   time.Now(),
+				Repo:   repoName,
+				Branch: branchList[res.BranchesIndex],
+				From:   badgeStates[prev.Build.Result],
+				To:     badgeStates[res.Build.Result],
+			}
+			statusChanges = append(statusChanges, change)
+			if o.CallbackUrl != "" {
+				pendingCallbackChanges = append(pendingCallbackChanges, change)
+			}
+		}
+	}
+	if max := o.RecentChanges; len(statusChanges) > max {
+		statusChanges = append([]StatusChange(nil), statusChanges[len(statusChanges)-max:]...)
+	}
+	if dropped := len(pendingCallbackChanges) - maxPendingCallbackChanges; dropped > 0 {
+		glog.Warningf("Dropping %d transitions not yet posted to the status callback", dropped)
+		pendingCallbackChanges = append([]StatusChange(nil), pendingCallbackChanges[dropped:]...)
+	}
+}
+
+// keepLastKnownStatus replaces the failed results by the state of the
+// previous results, marking them stale.
+func keepLastKnownStatus(results []CiResult, previous []CiResult) {
+	for i := range results {
+		if !results[i].Failed {
+			continue
+		}
+		for _, prev := range previous {
+			if prev.BranchesIndex == results[i].BranchesIndex && (!prev.Failed || prev.Stale) {
+				results[i].Build = prev.Build
+				results[i].BuildNumber = prev.BuildNumber
+				results[i].Stale = true
+			}
+		}
+	}
+}
+
+// notrunCiResults is used for repositories without fetched CI status yet.
+func notrunCiResults(branches []string) []CiResult {
+	results := make([]CiResult, len(branches))
+	for i, branch := range branches {
+		results[i] = CiResult{
+			BranchesIndex:           i,
+			BranchHtmlDoubleEncoded: url.QueryEscape(url.QueryEscape(branch)),
+			Build:                   &Badge{Result: 0, Image: badgeImage(0)},
+		}
+	}
+	return results
+}
+
+// jenkinsBuildStatusUrl is queried for the build status of a repository branch
+func jenkinsBuildStatusUrl(repoName string, branchHtmlDoubleEncoded string) string {
+	return currentOptions().JenkinsUrl + "/buildStatus/text?job=dcos-terraform%2F" + repoName + "%2F" + branchHtmlDoubleEncoded
+}
+
+// jenkinsLastBuildUrl is the JSON API of the last build of a repository branch
+func jenkinsLastBuildUrl(repoName string, branchHtmlDoubleEncoded string) string {
+	return jenkinsJobUrl(repoName, branchHtmlDoubleEncoded) + "lastBuild/api/json?tree=result,building,number"
+}
+
+// jenkinsMultibranchUrl is the JSON API listing the branch jobs of a repository
+// with their last build
+func jenkinsMultibranchUrl(repoName string) string {
+	return currentOptions().JenkinsUrl + "/job/dcos-terraform/job/" + repoName + "/api/json?tree=jobs[name,lastBuild[result,building,number]]"
+}
+
+// jenkinsJobUrl links to the Jenkins job of a repository branch
+func jenkinsJobUrl(repoName string, branchHtmlDoubleEncoded string) string {
+	return currentOptions().JenkinsUrl + "/job/dcos-terraform/job/" + repoName + "/job/" + branchHtmlDoubleEncoded + "/"
+}
+
+// actionsWorkflowUrl is the URL of the configured GitHub Actions workflow of a
+// repository
+func actionsWorkflowUrl(repo *github.Repository) string {
+	o := currentOptions()
+	repoUrl := repo.GetHTMLURL()
+	if repoUrl == "" {
+		repoUrl = "https://github.com/" + o.GitHubOrg + "/" + repo.GetName()
+	}
+	return repoUrl + "/actions/workflows/" + url.PathEscape(o.ActionsWorkflow)
+}
+
+// statusSnapshot returns the CI status of all tracked repositories ordered
+// by provider, private ones are left out unless includePrivate.
+func statusSnapshot(includePrivate bool) []RepoStatus {
+	dataMutex.RLock()
+	defer dataMutex.RUnlock()
+	list := make([]RepoStatus, 0)
+	for _, p := range provider {
+		for _, repo := range repos[p] {
+			if repo.GetPrivate() && !includePrivate {
+				continue
+			}
+			badges, ok := ciStatus[repo.GetName()]
+			if !ok {
+				badges = notrunCiResults(branches)
+			}
+			status := RepoStatus{
+				Name:     repo.GetName(),
+				Provider: p,
+				URL:      repo.GetHTMLURL(),
+				Branches: make([]BranchStatus, 0, len(badges)),
+			}
+			primary := primaryBranchIndex(branches)
+			for _, badge := range badges {
+				if badge.BranchesIndex == primary {
+					status.State = badgeStates[badge.Build.Result]
+				}
+				status.Branches = append(status.Branches, BranchStatus{
+					Branch: branches[badge.BranchesIndex],
+					State:  badgeStates[badge.Build.Result],
+					Result: badge.Build.Result,
+					Stale:  badge.Stale,
+					Build:  badge.BuildNumber,
+					Link:   jenkinsJobUrl(repo.GetName(), badge.BranchHtmlDoubleEncoded),
+				})
+			}
+			list = append(list, status)
+		}
+	}
+	return list
+}
+
+// refreshStats counts the runs of a refresh, a run failed if any upstream
+// request failed.
+type refreshStats struct {
+	mu        sync.Mutex
+	runs      int
+	succeeded int
+	failed    int
+	total     time.Duration
+}
+
+func (s *refreshStats) record(d time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs++
+	if failed {
+		s.failed++
+	} else {
+		s.succeeded++
+	}
+	s.total += d
+}
+
+func (s *refreshStats) snapshot() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	average := time.Duration(0)
+	if s.runs > 0 {
+		average = s.total / time.Duration(s.runs)
+	}
+	return map[string]interface{}{
+		"runs":           s.runs,
+		"succeeded":      s.succeeded,
+		"failed":         s.failed,
+		"average_millis": average.Nanoseconds() / int64(time.Millisecond),
+	}
+}
+
+var startTime = time.Now()
+var githubRefreshStats, ciRefreshStats refreshStats
+
+// statsHandler returns the uptime and the refresh statistics as JSON.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	stats := map[string]interface{}{
+		"started":        startTime.UTC().Format(time.RFC3339),
+		"uptime_seconds": int64(time.Since(startTime).Seconds()),
+		"github_refresh": githubRefreshStats.snapshot(),
+		"ci_refresh":     ciRefreshStats.snapshot(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		glog.Errorf("Failed to encode stats: %v", err)
+	}
+}
+
+// Summary is the compact overall status of /api/summary.
+type Summary struct {
+	Repos       int            `json:"repos"`
+	States      map[string]int `json:"states"`
+	Health      int            `json:"health"`
+	LastRefresh *time.Time     `json:"last_refresh,omitempty"`
+}
+
+// summarize counts the branch states of all repos. Health is the percentage of
+// passing branches of all branches having a build.
+func summarize() Summary {
+	list := statusSnapshot(!currentOptions().HidePrivate)
+	dataMutex.RLock()
+	refreshed := lastCiRefresh
+	dataMutex.RUnlock()
+
+	summary := Summary{Repos: len(list), States: make(map[string]int, len(badgeStates)), Health: 100}
+	for _, state := range badgeStates {
+		summary.States[state] = 0
+	}
+	built := 0
+	for _, repo := range list {
+		for _, b := range repo.Branches {
+			summary.States[b.State]++
+			if b.Result != 0 {
+				built++
+			}
+		}
+	}
+	if built > 0 {
+		summary.Health = summary.States["passing"] * 100 / built
+	}
+	if !refreshed.IsZero() {
+		summary.LastRefresh = &refreshed
+	}
+	return summary
+}
+
+// statusCallback is posted to --status-callback-url after every refresh, it
+// holds the transitions since the previous successful callback.
+type statusCallback struct {
+	Summary
+	Changes []StatusChange `json:"changes"`
+}
+
+// callbackMutex serializes the callbacks of the refresh loops
+var callbackMutex sync.Mutex
+
+// postStatusCallback posts the summary to --status-callback-url, retrying
+// with backoff. The body is signed with HMAC-SHA256 of --status-callback-secret
+// in the X-Statuspage-Signature header. It is a no-op without url.
+func postStatusCallback() {
+	o := currentOptions()
+	if o.CallbackUrl == "" || o.CiBackend == "actions-badge" {
+		// nothing is scraped to report
+		return
+	}
+	callbackMutex.Lock()
+	defer callbackMutex.Unlock()
+
+	payload := statusCallback{Summary: summarize(), Changes: make([]StatusChange, 0)}
+	dataMutex.RLock()
+	private := make(map[string]bool)
+	if o.HidePrivate {
+		private = privateRepos()
+	}
+	pending := pendingCallbackChanges
+	for _, c := range pending {
+		if !private[c.Repo] {
+			payload.Changes = append(payload.Changes, c)
+		}
+	}
+	dataMutex.RUnlock()
+	body, err := json.Marshal(payload)
+	CheckErrorFatal(err)
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err = sendStatusCallback(body)
+		statsd.incr("callback.requests", 1)
+		if err == nil {
+			dataMutex.Lock()
+			pendingCallbackChanges = removePosted(pendingCallbackChanges, pending)
+			dataMutex.Unlock()
+			return
+		}
+		statsd.incr("callback.errors", 1)
+		if attempt >= o.CallbackRetries {
+			glog.Errorf("Status callback failed, giving up: %v", err)
+			return
+		}
+		glog.Warningf("Status callback failed, retrying in %s: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-refreshCtx.Done():
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// removePosted returns the pending transitions newer than the last posted one.
+// If that got dropped meanwhile all remaining ones are newer.
+func removePosted(pending []StatusChange, posted []StatusChange) []StatusChange {
+	if len(posted) == 0 {
+		return pending
+	}
+	last := posted[len(posted)-1]
+	for i := len(pending) - 1; i >= 0; i-- {
+		if pending[i] == last {
+			return append([]StatusChange(nil), pending[i+1:]...)
+		}
+	}
+	return pending
+}
+
+func sendStatusCallback(body []byte) error {
+	o := currentOptions()
+	req, err := http.NewRequest("POST", o.CallbackUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.CallbackSecret != "" {
+		mac := hmac.New(sha256.New, []byte(o.CallbackSecret))
+		mac.Write(body)
+		req.Header.Set("X-Statuspage-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	res, err := callbackClient.Do(req.WithContext(refreshCtx))
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("callback responded %s", res.Status)
+	}
+	return nil
+}
+
+// badgeImage returns the image path of a Badge.Result
+func badgeImage(result int) string {
+	if dynamicBadges {
+		return "/badge/" + badgeStates[result] + ".svg"
+	}
+	return STATIC_DIR + fmt.Sprintf("images/%d-build-%s.svg", result, badgeStates[result])
+}
+
+// checkPrimaryBranch fails if --primary-branch is set but not among the
+// branches. --latest-branch-only may drop it, the first branch is primary then.
+func checkPrimaryBranch(list []string) error {
+	if primaryBranch == "" {
+		return nil
+	}
+	configured, _ := resolveBranches(list, false)
+	if !contains(configured, primaryBranch) {
+		return fmt.Errorf("Primary branch \"%s\" is not a configured branch", primaryBranch)
+	}
+	return nil
+}
+
+// primaryBranchIndex returns the index of --primary-branch, the first branch
+// if it is unset or not among the branches.
+func primaryBranchIndex(branchList []string) int {
+	for i, b := range branchList {
+		if b == primaryBranch {
+			return i
+		}
+	}
+	return 0
+}
+
+// markdownTable renders the status table of the repositories for the
+// branches with the given indexes.
+func markdownTable(repoList []*github.Repository, repoCiStatus map[string][]CiResult, repoOpenPrs map[string]int, branches []string, branchIndexes []int) []byte {
+	o := currentOptions()
+	var table []byte
+	head := []string{"Repository"}
+	if repoOpenPrs != nil {
+		head = append(head, "Open PRs")
+	}
+	primary := primaryBranchIndex(branches)
+	for _, i := range branchIndexes {
+		name := branches[i]
+		if alias, ok := branchAliases[branches[i]]; ok {
+			name = alias
+		}
+		// a | would split the column
+		name = escapeMarkdown(name)
+		if i == primary && len(branches) > 1 {
+			name = `<strong class="primary-branch">` + name + `</strong>`
+		}
+		head = append(head, name)
+	}
+	tablehead := []byte("| " + strings.Join(head, " | ") + " |\n")
+	tablesplit := []byte("|" + strings.Repeat(" --- |", len(head)) + "\n")
+	table = append(table, tablehead...)
+	table = append(table, tablesplit...)
+
+	status_badge_icon_prefix := "[![Build Status]("
+
+	for _, repo := range repoList {
+		badges, ok := repoCiStatus[*repo.Name]
+		if !ok {
+			badges = notrunCiResults(branches)
+		}
+
+		cells := []string{*repo.Name}
+		if !o.NoRepoLinks && repo.GetHTMLURL() != "" {
+			cells[0] = "[" + *repo.Name + "](" + repo.GetHTMLURL() + ")"
+		}
+		for _, label := range repoBadges[*repo.Name] {
+			cells[0] += ` <span class="badge badge-secondary">` + escapeMarkdown(label) + `</span>`
+		}
+		if repoOpenPrs != nil {
+			if count, ok := repoOpenPrs[*repo.Name]; ok && count >= 0 {
+				cells = append(cells, strconv.Itoa(count))
+			} else {
+				cells = append(cells, "-")
+			}
+		}
+		for _, i := range branchIndexes {
+			if o.CiBackend == "actions-badge" {
+				// GitHub renders the badge, nothing has been scraped
+				workflowUrl := actionsWorkflowUrl(repo)
+				cells = append(cells, status_badge_icon_prefix+workflowUrl+"/badge.svg?branch="+url.QueryEscape(branches[i])+")]("+workflowUrl+"?query="+url.QueryEscape("branch:"+branches[i])+")")
+				continue
+			}
+			for _, badge := range badges {
+				if badge.BranchesIndex != i {
+					continue
+				}
+				if glog.V(9) {
+					glog.Infof("Branch \"%s\" gets \"%s\"", branches[badge.BranchesIndex], badge.Build.Image)
+				}
+				cell := status_badge_icon_prefix + badge.Build.Image + ")](" + jenkinsJobUrl(*repo.Name, badge.BranchHtmlDoubleEncoded) + ")"
+				if o.ShowBuildNumber && badge.BuildNumber > 0 {
+					cell += fmt.Sprintf(" [#%d](%s%d/)", badge.BuildNumber, jenkinsJobUrl(*repo.Name, badge.BranchHtmlDoubleEncoded), badge.BuildNumber)
+				}
+				if badge.Stale {
+					cell += " *stale*"
+				}
+				cells = append(cells, cell)
+			}
+		}
+		table = append(table, "| "+strings.Join(cells, " | ")+" |\n"...)
+	}
+	return table
+}
+
+// limitRepos keeps the first max repos ordered by provider and name, the repos
+// keep their order within the providers. It returns the number of kept repos.
+func limitRepos(providerRepos map[string][]*github.Repository, providerList []string, max int) (map[string][]*github.Repository, int) {
+	limited := make(map[string][]*github.Repository, len(providerRepos))
+	kept := 0
+	for _, p := range providerList {
+		names := make([]string, 0, len(providerRepos[p]))
+		for _, repo := range providerRepos[p] {
+			names = append(names, repo.GetName())
+		}
+		sort.Strings(names)
+		if len(names) > max-kept {
+			names = names[:max-kept]
+		}
+		keep := make(map[string]bool, len(names))
+		for _, name := range names {
+			keep[name] = true
+		}
+		for _, repo := range providerRepos[p] {
+			if keep[repo.GetName()] {
+				limited[p] = append(limited[p], repo)
+			}
+		}
+		kept += len(names)
+	}
+	return limited, kept
+}
+
+// usedBranches returns the branch indexes at least one of the repositories
+// has a build state other than not run for. All are used until the CI status
+// of any repository is fetched, e.g. after the warmup timed out.
+func usedBranches(repoList []*github.Repository, repoCiStatus map[string][]CiResult, branchIndexes []int) []int {
+	fetched := false
+	for _, repo := range repoList {
+		if _, ok := repoCiStatus[*repo.Name]; ok {
+			fetched = true
+			break
+		}
+	}
+	if !fetched {
+		return branchIndexes
+	}
+	var used []int
+	for _, i := range branchIndexes {
+	repoLoop:
+		for _, repo := range repoList {
+			for _, badge := range repoCiStatus[*repo.Name] {
+				if badge.BranchesIndex == i && badge.Build.Result != 0 {
+					used = append(used, i)
+					break repoLoop
+				}
+			}
+		}
+	}
+	return used
+}
+
+func markdownContent() []byte {
+	o := currentOptions()
+	dataMutex.RLock()
+	providerRepos := make(map[string][]*github.Repository, len(repos))
+	for p, r := range repos {
+		providerRepos[p] = r
+	}
+	repoCiStatus := make(map[string][]CiResult, len(ciStatus))
+	for name, c := range ciStatus {
+		repoCiStatus[name] = c
+	}
+	var repoOpenPrs map[string]int
+	if o.ShowOpenPrs {
+		repoOpenPrs = make(map[string]int, len(openPrs))
+		for name, count := range openPrs {
+			repoOpenPrs[name] = count
+		}
+	}
+	providerList := provider
+	branchList := branches
+	groupBy := o.GroupBy
+	strict := o.Strict
+	prune := o.PruneEmptyColumns
+	maxRepos := o.MaxRepos
+	private := make(map[string]bool)
+	if o.HidePrivate {
+		private = privateRepos()
+		for p, list := range providerRepos {
+			public := make([]*github.Repository, 0, len(list))
+			for _, repo := range list {
+				if !private[repo.GetName()] {
+					public = append(public, repo)
+				}
+			}
+			providerRepos[p] = public
+		}
+	}
+	var changes []StatusChange
+	if o.ShowRecentChanges {
+		for _, c := range statusChanges {
+			if !private[c.Repo] {
+				changes = append(changes, c)
+			}
+		}
+	}
+	dataMutex.RUnlock()
+
+	shown, total := 0, 0
+	for _, p := range providerList {
+		total += len(providerRepos[p])
+	}
+	if maxRepos > 0 && total > maxRepos {
+		providerRepos, shown = limitRepos(providerRepos, providerList, maxRepos)
+	}
+
+	if glog.V(5) {
+		for _, p := range providerList {
+			glog.Infof("Repositories "+p+": %d", len(providerRepos[p]))
+		}
+	}
+
+	var md []byte
+	separator := []byte("---\n")
+	topic := []byte("# " + PAGE_TITLE + "\n")
+	md = append(md, topic...)
+	if shown > 0 {
+		md = append(md, fmt.Sprintf("*Showing %d of %d repos.*\n\n", shown, total)...)
+	}
+
+	allBranches := make([]int, len(branchList))
+	for i := range branchList {
+		allBranches[i] = i
+	}
+
+	sections := make(map[string][]byte, len(providerList))
+	for _, p := range providerList {
+		var section []byte
+		section = append(section, separator...)
+		providers := []byte("### Provider: **" + p + "**\n")
+		section = append(section, providers...)
+		columns := allBranches
+		if prune && o.CiBackend == "jenkins" {
+			columns = usedBranches(providerRepos[p], repoCiStatus, allBranches)
+		}
+		if groupBy == "provider,release" {
+			for _, i := range columns {
+				section = append(section, "#### Release: **"+branchList[i]+"**\n"...)
+				section = append(section, markdownTable(providerRepos[p], repoCiStatus, repoOpenPrs, branchList, []int{i})...)
+			}
+		} else {
+			section = append(section, markdownTable(providerRepos[p], repoCiStatus, repoOpenPrs, branchList, columns)...)
+		}
+		sections[p] = section
+		if groupBy != "release,provider" {
+			md = append(md, section...)
+		}
+	}
+
+	if groupBy == "release,provider" {
+		for i, b := range branchList {
+			md = append(md, separator...)
+			md = append(md, "### Release: **"+b+"**\n"...)
+			for _, p := range providerList {
+				if prune && o.CiBackend == "jenkins" && len(usedBranches(providerRepos[p], repoCiStatus, []int{i})) == 0 {
+					continue
+				}
+				md = append(md, "#### Provider: **"+p+"**\n"...)
+				md = append(md, markdownTable(providerRepos[p], repoCiStatus, repoOpenPrs, branchList, []int{i})...)
+			}
+		}
+	}
+
+	if len(changes) > 0 {
+		md = append(md, separator...)
+		md = append(md, "### Recent changes\n"...)
+		for i := len(changes) - 1; i >= 0; i-- {
+			c := changes[i]
+			md = append(md, fmt.Sprintf("- %s **%s** %s: %s → %s\n", c.Time.UTC().Format("2006-01-02 15:04 MST"), c.Repo, c.Branch, c.From, c.To)...)
+		}
+	}
+
+	dataMutex.RLock()
+	changed := !bytes.Equal(md, markdownCache)
+	dataMutex.RUnlock()
+	if changed {
+		if shown > 0 {
+			glog.Warningf("Only showing %d of %d repos, raise --max-repos to show all", shown, total)
+		}
+		if err := validateHtml(renderPage(md)); err != nil {
+			if strict {
+				glog.Fatalf("Generated HTML is malformed: %v", err)
+			}
+			glog.Warningf("Generated HTML is malformed: %v", err)
+		}
+	}
+
+	dataMutex.Lock()
+	markdownCache = md
+	markdownProviderCache = sections
+	if changed {
+		markdownVersion++
+	}
+	dataMutex.Unlock()
+	if changed {
+		renderCache.Purge()
+	}
+	return nil
+}
+
+func renderMarkdownHtml() string {
+	dataMutex.RLock()
+	defer dataMutex.RUnlock()
+	return renderPage(markdownCache)
+}
+
+// renderPage renders markdown as complete HTML page
+func renderPage(md []byte) string {
+	md = append(md[:len(md):len(md)], pageFooter()...)
+	flags := html.CommonFlags | html.CompletePage | html.HrefTargetBlank
+	opts := html.RendererOptions{
+		Title:     PAGE_TITLE,
+		Flags:     flags,
+		CSS:       STATIC_DIR + "css/" + STATIC_CSS_FILE,
+		Icon:      "/favicon.ico",
+		Head:      []byte(pageHead),
+		Generator: GENERATOR,
+	}
+	renderer := html.NewRenderer(opts)
+	return string(markdown.ToHTML(md, nil, renderer))
+}
+
+// pageHead is HEAD_EXTRA with the --extra-css stylesheets
+var pageHead = HEAD_EXTRA
+
+// pageFooter shows the running version linked to its source commit and where
+// to report issues
+func pageFooter() string {
+	o := currentOptions()
+	footer := "statuspage " + escapeMarkdown(version)
+	if commit != "" {
+		footer = "statuspage [" + escapeMarkdown(version) + "](" + SOURCE_URL + "/commit/" + url.PathEscape(commit) + ")"
+	}
+	if o.IssuesUrl != "" {
+		footer += " · [Report an issue](" + o.IssuesUrl + ")"
+	}
+	if o.StatsInFooter {
+		// rendered pages are cached, only the start time stays correct
+		footer += " · up since " + startTime.UTC().Format("2006-01-02 15:04 MST") + " · [stats](/stats)"
+	}
+	return "\n---\n\n<small class=\"text-muted\">" + footer + "</small>\n"
+}
+
+// voidElements never have an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// validateHtml checks that all elements of the document are properly nested
+// and closed. The HTML5 parser accepts about anything, so the tokens are
+// checked instead.
+func validateHtml(doc string) error {
+	var open []string
+	z := nethtml.NewTokenizer(strings.NewReader(doc))
+	for {
+		switch z.Next() {
+		case nethtml.ErrorToken:
+			if z.Err() != io.EOF {
+				return z.Err()
+			}
+			if len(open) > 0 {
+				return fmt.Errorf("unclosed element <%s>", open[len(open)-1])
+			}
+			return nil
+		case nethtml.StartTagToken:
+			name, _ := z.TagName()
+			if !voidElements[string(name)] {
+				open = append(open, string(name))
+			}
+		case nethtml.EndTagToken:
+			name, _ := z.TagName()
+			if voidElements[string(name)] {
+				continue
+			}
+			if len(open) == 0 || open[len(open)-1] != string(name) {
+				return fmt.Errorf("unexpected end tag </%s>", name)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+}
+
+// renderEmbedHtml renders the tables of the given providers as HTML fragment
+// without the page shell, suitable for iframes or server side includes.
+func renderEmbedHtml(providers []string) string {
+	opts := html.RendererOptions{
+		Flags: html.CommonFlags | html.HrefTargetBlank,
+	}
+	renderer := html.NewRenderer(opts)
+	var md []byte
+	dataMutex.RLock()
+	for _, p := range providers {
+		md = append(md, markdownProviderCache[p]...)
+	}
+	dataMutex.RUnlock()
+	return string(markdown.ToHTML(md, nil, renderer))
+}
+
+// handler serves the status page in the representation requested by the
+// Accept header, HTML is the default.
+func handler(w http.ResponseWriter, r *http.Request) {
+	if statusPending(w, r, true) {
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	switch negotiateContentType(r.Header.Get("Accept")) {
+	case "application/json":
+		statusApiHandler(w, r)
+	case "text/markdown":
+		rawHandler(w, r)
+	case "text/csv":
+		csvHandler(w, r)
+	default:
+		htmlHandler(w, r)
+	}
+}
+
+// statusPending answers 503 with Retry-After until the first markdown is
+// generated, the loading page instead if withLoadingPage and it is enabled.
+// It returns false once the status can be served.
+func statusPending(w http.ResponseWriter, r *http.Request, withLoadingPage bool) bool {
+	o := currentOptions()
+	dataMutex.RLock()
+	empty := len(markdownCache) == 0
+	dataMutex.RUnlock()
+	if !empty {
+		return false
+	}
+	seconds := int(o.RetryAfter / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	if withLoadingPage && o.LoadingPage && negotiateContentType(r.Header.Get("Accept")) == "text/html" {
+		loadingPage(w, seconds)
+		return true
+	}
+	http.Error(w, "Status is being fetched, please retry later", http.StatusServiceUnavailable)
+	return true
+}
+
+// loadingPage shows the progress of the initial refresh, the page reloads
+// itself every seconds.
+func loadingPage(w http.ResponseWriter, seconds int) {
+	progress := "fetching repositories"
+	if total := atomic.LoadInt32(&progressTotal); total > 0 {
+		progress = fmt.Sprintf("fetched %d of %d repos", atomic.LoadInt32(&progressFetched), total)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+  <title>%s</title>
+  <meta http-equiv="refresh" content="%d">
+  <link rel="stylesheet" type="text/css" href="%s">
+</head>
+<body>
+<p>Loading status… (%s)</p>
+</body>
+</html>
+`, PAGE_TITLE, seconds, STATIC_DIR+"css/"+STATIC_CSS_FILE, progress)
+}
+
+// htmlHandler serves the status page, optionally limited to the providers
+// given by ?provider=.
+func htmlHandler(w http.ResponseWriter, r *http.Request) {
+	providers, err := providerFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "max-age=600")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if providers == nil {
+		fmt.Fprint(w, cachedRender("page", renderMarkdownHtml))
+		return
+	}
+	fmt.Fprint(w, cachedRender("page|"+strings.Join(providers, ","), func() string {
+		md := []byte("# " + PAGE_TITLE + "\n")
+		dataMutex.RLock()
+		for _, p := range providers {
+			md = append(md, markdownProviderCache[p]...)
+		}
+		dataMutex.RUnlock()
+		return renderPage(md)
+	}))
+}
+
+// cachedRender returns the view of key rendered from the current markdown,
+// render is only called on a cache miss.
+func cachedRender(key string, render func() string) string {
+	dataMutex.RLock()
+	mdVersion := markdownVersion
+	dataMutex.RUnlock()
+	versionedKey := strconv.Itoa(mdVersion) + "|" + key
+	if cached, ok := renderCache.Get(versionedKey); ok {
+		return cached.(string)
+	}
+	// concurrent requests of the same view wait for a single render
+	rendered, _, _ := renderGroup.Do(versionedKey, func() (interface{}, error) {
+		if cached, ok := renderCache.Get(versionedKey); ok {
+			return cached, nil
+		}
+		rendered := render()
+		renderCache.Add(versionedKey, rendered)
+		return rendered, nil
+	})
+	return rendered.(string)
+}
+
+// providerFilter returns the providers given by ?provider=, nil if there is
+// no filter.
+func providerFilter(r *http.Request) ([]string, error) {
+	filter, ok := r.URL.Query()["provider"]
+	if !ok {
+		return nil, nil
+	}
+	dataMutex.RLock()
+	configured := provider
+	dataMutex.RUnlock()
+	providers := make([]string, 0, len(filter))
+	for _, p := range filter {
+		if !contains(configured, p) {
+			return nil, fmt.Errorf("Unknown provider \"%s\"", p)
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+// negotiateContentType picks the supported media type with the highest
+// quality from an Accept header, text/html if none matches.
+func negotiateContentType(accept string) string {
+	best := "text/html"
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		switch mediaType {
+		case "text/html", "application/json", "text/markdown", "text/csv":
+		default:
+			continue
+		}
+		if q > bestQ {
+			best = mediaType
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// statusScraped answers 501 unless the CI status is scraped, the badges of
+// --ci-backend=actions-badge are embedded without the status being known.
+func statusScraped(w http.ResponseWriter) bool {
+	if currentOptions().CiBackend == "actions-badge" {
+		http.Error(w, "The CI status is not available with --ci-backend=actions-badge", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+// statusApiHandler returns the CI status of all tracked repositories as JSON.
+func statusApiHandler(w http.ResponseWriter, r *http.Request) {
+	if !statusScraped(w) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(statusSnapshot(showPrivate(r))); err != nil {
+		glog.Errorf("Failed to encode status: %v", err)
+	}
+}
+
+// summaryApiHandler returns the compact overall status as JSON.
+func summaryApiHandler(w http.ResponseWriter, r *http.Request) {
+	if !statusScraped(w) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summarize()); err != nil {
+		glog.Errorf("Failed to encode summary: %v", err)
+	}
+}
+
+// rawHandler returns the generated markdown.
+func rawHandler(w http.ResponseWriter, r *http.Request) {
+	if statusPending(w, r, false) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	dataMutex.RLock()
+	md := markdownCache
+	dataMutex.RUnlock()
+	w.Write(md)
+}
+
+// csvHandler returns one line per repository branch as CSV.
+func csvHandler(w http.ResponseWriter, r *http.Request) {
+	if !statusScraped(w) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"provider", "repository", "branch", "state", "link"})
+	for _, status := range statusSnapshot(showPrivate(r)) {
+		for _, b := range status.Branches {
+			cw.Write([]string{status.Provider, status.Name, b.Branch, b.State, b.Link})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		glog.Errorf("Failed to write CSV: %v", err)
+	}
+}
+
+// embedHandler serves the provider tables as HTML fragment, optionally limited
+// to the providers given by ?provider=.
+func embedHandler(w http.ResponseWriter, r *http.Request) {
+	providers, err := providerFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	// a blank fragment must not be cached
+	if statusPending(w, r, false) {
+		return
+	}
+	if providers == nil {
+		dataMutex.RLock()
+		providers = provider
+		dataMutex.RUnlock()
+	}
+	w.Header().Set("Cache-Control", "max-age=600")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, cachedRender("embed|"+strings.Join(providers, ","), func() string {
+		return renderEmbedHtml(providers)
+	}))
+}
+
+// badgeHandler generates the SVG badge of a state with the configured color.
+func badgeHandler(w http.ResponseWriter, r *http.Request) {
+	state := mux.Vars(r)["state"]
+	for i, s := range badgeStates {
+		if s == state {
+			style := badgeStyles[i]
+			w.Header().Set("Cache-Control", "max-age=3600")
+			w.Header().Set("Content-Type", "image/svg+xml")
+			fmt.Fprintf(w, BADGE_SVG, 47+style.Width, style.Color, style.Width, 46+style.Width/2, style.Message)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// limitBody limits the request body of write endpoints to MaxBodyBytes
+func limitBody(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, currentOptions().MaxBodyBytes)
+		h(w, r)
+	}
+}
+
+// requireAdmin only passes requests carrying the configured admin token
+func requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if currentOptions().AdminToken == "" {
+			http.Error(w, "Admin endpoints are disabled, no admin token configured", http.StatusForbidden)
+			return
+		}
+		if !isAdmin(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// isAdmin checks the request for the bearer admin token
+func isAdmin(r *http.Request) bool {
+	o := currentOptions()
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return o.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(o.AdminToken)) == 1
+}
+
+// privateRepos returns the names of the tracked private repos. Must be called
+// with dataMutex held.
+func privateRepos() map[string]bool {
+	private := make(map[string]bool)
+	for _, list := range repos {
+		for _, repo := range list {
+			if repo.GetPrivate() {
+				private[repo.GetName()] = true
+			}
+		}
+	}
+	return private
+}
+
+// showPrivate reports if private repos are shown to the request, with
+// --hide-private-in-output only to admins.
+func showPrivate(r *http.Request) bool {
+	return !currentOptions().HidePrivate || isAdmin(r)
+}
+
+// reloadMutex serializes /admin/reload
+var reloadMutex sync.Mutex
+
+// reloadHandler re-reads the config file, env and CLI parameters and applies
+// the changed options that are safe to change at runtime. It responds with a
+// JSON diff of the changes, or 409 if an option requiring a restart changed.
+func reloadHandler(w http.ResponseWriter, r *http.Request) {
+	// concurrent reloads would start refresh loops of the same generation
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	current := *currentOptions()
+	var fresh options
+	freshValue := reflect.ValueOf(&fresh).Elem()
+	if err := parseOptions(&fresh, os.Args, flags.None); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	enforceMinRefresh(&fresh)
+	newProvider, err := resolveProviders(fresh.Providers, fresh.UnknownProvider, alwaysIncludeSection(fresh.AlwaysInclude, fresh.AlwaysSection))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newProviderCiRefresh, err := parseProviderCiRefresh(fresh.ProviderCiRefresh, newProvider, fresh.MinRefresh)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newBranches, err := resolveBranches(fresh.Branches, fresh.LatestBranchOnly)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkPrimaryBranch(fresh.Branches); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes := make(map[string]optionChange)
+	var restartRequired []string
+	currentValue := reflect.ValueOf(current)
+	for i := 0; i < freshValue.NumField(); i++ {
+		name := freshValue.Type().Field(i).Name
+		if reflect.DeepEqual(currentValue.Field(i).Interface(), freshValue.Field(i).Interface()) {
+			continue
+		}
+		if reloadableOptions[name] {
+			changes[name] = optionChange{Old: currentValue.Field(i).Interface(), New: freshValue.Field(i).Interface()}
+		} else {
+			restartRequired = append(restartRequired, name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(restartRequired) > 0 {
+		glog.Warningf("Reload rejected, changed options require a restart: %s", strings.Join(restartRequired, ", "))
+		w.WriteHeader(http.StatusConflict)
+		if err := json.NewEncoder(w).Encode(map[string][]string{"restart_required": restartRequired}); err != nil {
+			glog.Errorf("Failed to encode reload result: %v", err)
+		}
+		return
+	}
+
+	if len(changes) > 0 {
+		dataMutex.Lock()
+		optionsSnapshot.Store(&fresh)
+		provider = newProvider
+		if !reflect.DeepEqual(branches, newBranches) {
+			ciStatus = make(map[string][]CiResult)
+		}
+		branches = newBranches
+		providerCiRefresh = newProviderCiRefresh
+		refreshGeneration++
+		dataMutex.Unlock()
+
+		changed := make([]string, 0, len(changes))
+		for name := range changes {
+			changed = append(changed, name)
+		}
+		sort.Strings(changed)
+		glog.Infof("Configuration reloaded, changed options: %s", strings.Join(changed, ", "))
+		startCiRefreshLoops()
+		go refreshAll()
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]map[string]optionChange{"changed": changes}); err != nil {
+		glog.Errorf("Failed to encode reload result: %v", err)
+	}
+}
+
+// debugJenkinsHandler performs a live Jenkins build status query and returns
+// the raw response to troubleshoot the shown badges.
+func debugJenkinsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	branchHtmlDoubleEncoded := url.QueryEscape(url.QueryEscape(vars["branch"]))
+	// the same request the badges are made of
+	var jenkinsUrl string
+	switch currentOptions().JenkinsMode {
+	case "json":
+		jenkinsUrl = jenkinsLastBuildUrl(vars["repo"], branchHtmlDoubleEncoded)
+	case "multibranch":
+		jenkinsUrl = jenkinsMultibranchUrl(vars["repo"])
+	default:
+		jenkinsUrl = jenkinsBuildStatusUrl(vars["repo"], branchHtmlDoubleEncoded)
+	}
+	result := struct {
+		URL        string `json:"url"`
+		StatusCode int    `json:"status_code"`
+		Body       string `json:"body"`
+		Error      string `json:"error,omitempty"`
+	}{URL: jenkinsUrl}
+
+	res, err := jenkinsClient.Get(jenkinsUrl)
+	if err == nil {
+		var body []byte
+		body, err = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		result.StatusCode = res.StatusCode
+		result.Body = string(body)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		glog.Errorf("Failed to encode debug result: %v", err)
+	}
+}
+
+// debugConfigHandler returns the effective options by their long name and the
+// resolved providers and branches. Options tagged secret are redacted.
+func debugConfigHandler(w http.ResponseWriter, r *http.Request) {
+	dataMutex.RLock()
+	options := redactedOptions(*currentOptions())
+	resolved := map[string][]string{"providers": provider, "branches": branches}
+	dataMutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"options": options, "resolved": resolved}); err != nil {
+		glog.Errorf("Failed to encode config: %v", err)
+	}
+}
+
+// redactedOptions maps the fields of an options struct by their long name.
+// Values of fields tagged secret are replaced by ***, lists of key=value pairs
+// keep their keys.
+func redactedOptions(options interface{}) map[string]interface{} {
+	v := reflect.ValueOf(options)
+	result := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := field.Tag.Get("long")
+		if name == "" {
+			name = field.Name
+		}
+		value := v.Field(i).Interface()
+		if field.Tag.Get("secret") == "true" {
+			switch secret := value.(type) {
+			case string:
+				if secret != "" {
+					value = "***"
+				}
+			case []string:
+				redacted := make([]string, len(secret))
+				for j, s := range secret {
+					if key, _, ok := splitKeyValue(s); ok {
+						redacted[j] = key + "=***"
+					} else {
+						redacted[j] = "***"
+					}
+				}
+				value = redacted
+			default:
+				value = "***"
+			}
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// readinessHandler reports 200 once warmup is done, 503 before
+func readinessHandler(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&ready) == 0 {
+		http.Error(w, "warming up", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// assetsHealthHandler reports the result of the last static assets check.
+func assetsHealthHandler(w http.ResponseWriter, r *http.Request) {
+	if msg, _ := assetsError.Load().(string); msg != "" {
+		http.Error(w, msg, http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// checkAssets verifies that the static dir, the favicons and the CSS file are
+// still readable, e.g. the volume hasn't been unmounted.
+func checkAssets() {
+	msg := ""
+	for _, dir := range []string{STATIC_DIR, STATIC_DIR + "images/favicon"} {
+		if _, err := ioutil.ReadDir(dir); err != nil {
+			msg = err.Error()
+			break
+		}
+	}
+	if msg == "" {
+		if f, err := os.Open(STATIC_DIR + "css/" + STATIC_CSS_FILE); err != nil {
+			msg = err.Error()
+		} else {
+			_, err = f.Read(make([]byte, 1))
+			f.Close()
+			if err != nil {
+				msg = fmt.Sprintf("read %s: %v", STATIC_CSS_FILE, err)
+			}
+		}
+	}
+	previous, _ := assetsError.Load().(string)
+	if msg != "" && msg != previous {
+		glog.Errorf("Static assets check failed: %s", msg)
+	} else if msg == "" && previous != "" {
+		glog.Info("Static assets are readable again")
+	}
+	assetsError.Store(msg)
+}
+
+// robotsHandler keeps crawlers from indexing the page unless --robots-allow
+func robotsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if currentOptions().RobotsAllow {
+		w.Write([]byte("User-agent: *\nDisallow:\n"))
+		return
+	}
+	w.Write([]byte("User-agent: *\nDisallow: /\n"))
+}
+
+func livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// reposApiHandler returns the tracked repositories with their provider as JSON.
+func reposApiHandler(w http.ResponseWriter, r *http.Request) {
+	includePrivate := showPrivate(r)
+	dataMutex.RLock()
+	list := make([]RepoInfo, 0)
+	for _, p := range provider {
+		for _, repo := range repos[p] {
+			if repo.GetPrivate() && !includePrivate {
+				continue
+			}
+			list = append(list, RepoInfo{
+				Name:          repo.GetName(),
+				Provider:      p,
+				DefaultBranch: repo.GetDefaultBranch(),
+				Archived:      repo.GetArchived(),
+				URL:           repo.GetHTMLURL(),
+			})
+		}
+	}
+	dataMutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		glog.Errorf("Failed to encode repos: %v", err)
+	}
+}
+
+func faviconHandler(w http.ResponseWriter, r *http.Request) {
+	http.ServeFile(w, r, STATIC_DIR+"images/favicon"+r.URL.Path)
+}
+
+// parseProviderCiRefresh turns provider=duration pairs into a map of CI refresh
+// intervals per provider.
+func parseProviderCiRefresh(pairs []string, providers []string, minimum time.Duration) (map[string]time.Duration, error) {
+	refresh := make(map[string]time.Duration, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := splitKeyValue(pair)
+		if !ok {
+			return nil, fmt.Errorf("Invalid provider CI refresh \"%s\", expected provider=duration", pair)
+		}
+		if !contains(providers, key) {
+			return nil, fmt.Errorf("Unknown provider \"%s\" in provider CI refresh", key)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("Invalid duration \"%s\" for provider \"%s\"", value, key)
+		}
+		if d < minimum {
+			glog.Warningf("CI refresh %s of provider %s is below the minimum, using %s", d, key, minimum)
+			d = minimum
+		}
+		refresh[key] = d
+		glog.Infof("CI status of provider %s is refreshed every %s", key, d)
+	}
+	return refresh, nil
+}
+
+// resolveProviders returns the provider sections, the catch-all section for
+// unknown providers and the section of always included repos come last.
+func resolveProviders(list []string, unknown string, always string) ([]string, error) {
+	providers := append([]string{}, list...)
+	if unknown != "" {
+		if contains(providers, unknown) {
+			return nil, fmt.Errorf("Unknown provider section \"%s\" is a configured provider", unknown)
+		}
+		providers = append(providers, unknown)
+	}
+	if always != "" && !contains(providers, always) {
+		providers = append(providers, always)
+	}
+	return providers, nil
+}
+
+// alwaysIncludeSection returns the section of always included repos, empty if
+// there are none.
+func alwaysIncludeSection(alwaysInclude []string, section string) string {
+	if len(alwaysInclude) == 0 {
+		return ""
+	}
+	return section
+}
+
+// resolveBranches returns the normalized and deduplicated branches, only the
+// latest one if latestOnly is set.
+func resolveBranches(list []string, latestOnly bool) ([]string, error) {
+	normalized := make([]string, 0, len(list))
+	for _, b := range list {
+		b = strings.TrimPrefix(strings.TrimSpace(b), "refs/heads/")
+		if err := validateBranch(b); err != nil {
+			return nil, fmt.Errorf("Invalid branch \"%s\": %v", b, err)
+		}
+		normalized = append(normalized, b)
+	}
+	branches := dedupeBranches(normalized)
+	if latestOnly && len(branches) > 1 {
+		branches = []string{latestBranch(branches)}
+		glog.Infof("Only showing latest branch %s", branches[0])
+	}
+	return branches, nil
+}
+
+// maxBranchLength is the name limit of most file systems git stores the
+// branches on
+const maxBranchLength = 255
+
+// validateBranch checks a branch name for the rules of
+// git check-ref-format, which also keeps the Jenkins URLs well-formed.
+func validateBranch(b string) error {
+	switch {
+	case b == "":
+		return fmt.Errorf("empty name")
+	case len(b) > maxBranchLength:
+		return fmt.Errorf("longer than %d bytes", maxBranchLength)
+	case !utf8.ValidString(b):
+		return fmt.Errorf("not valid UTF-8")
+	case strings.Contains(b, "..") || strings.Contains(b, "//") || strings.Contains(b, "@{"):
+		return fmt.Errorf("contains \"..\", \"//\" or \"@{\"")
+	case strings.HasPrefix(b, "/") || strings.HasSuffix(b, "/") || strings.HasSuffix(b, ".") || strings.HasSuffix(b, ".lock"):
+		return fmt.Errorf("starts or ends with a slash, or ends with a dot or .lock")
+	}
+	for _, r := range b {
+		if unicode.IsControl(r) || unicode.IsSpace(r) || strings.ContainsRune("~^:?*[\\", r) {
+			return fmt.Errorf("contains %q", r)
+		}
+	}
+	return nil
+}
+
+// parseBranchAliases turns branch=alias pairs into a map used for the table
+// headers only, Jenkins queries and links keep the full branch name.
+func parseBranchAliases(pairs []string, configured []string) map[string]string {
+	aliases := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		branch, alias, ok := splitKeyValue(pair)
+		alias = strings.TrimSpace(alias)
+		if !ok || alias == "" {
+			ErrorPrintHelpAndExit(&Options, fmt.Sprintf("Invalid branch alias \"%s\", expected branch=alias", pair))
+		}
+		// the same normalization as --branch
+		branch = strings.TrimPrefix(strings.TrimSpace(branch), "refs/heads/")
+		if !contains(configured, branch) {
+			glog.Warningf("Branch alias \"%s\" is for %s, which is not a configured branch", pair, branch)
+		}
+		aliases[branch] = alias
+	}
+	return aliases
+}
+
+// parseRepoBadges turns repo=label pairs into the labels per repo, a repo may
+// have multiple labels.
+func parseRepoBadges(pairs []string) map[string][]string {
+	labels := make(map[string][]string)
+	for _, pair := range pairs {
+		repo, label, ok := splitKeyValue(pair)
+		label = strings.TrimSpace(label)
+		if !ok || label == "" {
+			ErrorPrintHelpAndExit(&Options, fmt.Sprintf("Invalid repo badge \"%s\", expected repo=label", pair))
+		}
+		labels[repo] = append(labels[repo], label)
+	}
+	return labels
+}
+
+var markdownEscapeRegexp = regexp.MustCompile("[\\\\`*_{}\\[\\]()#+\\-.!:|&<>~]")
+
+// escapeMarkdown backslash escapes configured text so it is shown literally,
+// e.g. pipes would end a table cell.
+func escapeMarkdown(s string) string {
+	return markdownEscapeRegexp.ReplaceAllString(s, `\$0`)
+}
+
+// staticPath resolves a file relative to the static dir, e.g. css/custom.css
+// or /static/css/custom.css, to an existing file under the static dir.
+func staticPath(file string) (string, error) {
+	rel := path.Clean(strings.TrimPrefix(file, STATIC_DIR))
+	if path.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("not a file under %s", STATIC_DIR)
+	}
+	info, err := os.Stat(STATIC_DIR + rel)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", STATIC_DIR+rel)
+	}
+	return STATIC_DIR + rel, nil
+}
+
+// splitKeyValue splits a key=value pair, the key is trimmed and must not be
+// empty.
+func splitKeyValue(pair string) (string, string, bool) {
+	kv := strings.SplitN(pair, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return "", "", false
+	}
+	return strings.TrimSpace(kv[0]), kv[1], true
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// configureBadgeColors applies the configured colors to the badge styles
+func configureBadgeColors() {
+	colors := []string{Options.ColorNotrun, Options.ColorPassing, Options.ColorRunning, Options.ColorFailing, Options.ColorAborted, Options.ColorUnstable}
+	for i, color := range colors {
+		if color == "" {
+			continue
+		}
+		if !colorRegexp.MatchString(color) {
+			ErrorPrintHelpAndExit(&Options, fmt.Sprintf("Invalid color \"%s\" for %s badges, expected e.g. #44cc11", color, badgeStates[i]))
+		}
+		badgeStyles[i].Color = color
+		dynamicBadges = true
+	}
+}
+
+// dedupeBranches removes duplicate branches while preserving their order.
+func dedupeBranches(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, b := range in {
+		if seen[b] {
+			glog.Warningf("Branch \"%s\" is configured more than once, ignoring duplicate", b)
+			continue
+		}
+		seen[b] = true
+		out = append(out, b)
+	}
+	return out
+}
+
+var releaseLineRegexp = regexp.MustCompile(`(\d+)\.(\d+)(\.x)?$`)
+
+// latestBranch returns the branch with the highest release line, e.g.
+// support/0.2.x over support/0.1.x. The first branch wins if none of them
+// look like a release line.
+func latestBranch(in []string) string {
+	latest := in[0]
+	latestMajor, latestMinor := -1, -1
+	for _, b := range in {
+		m := releaseLineRegexp.FindStringSubmatch(b)
+		if m == nil {
+			continue
+		}
+		major, _ := strconv.Atoi(m[1])
+		minor, _ := strconv.Atoi(m[2])
+		if major > latestMajor || (major == latestMajor && minor > latestMinor) {
+			latest = b
+			latestMajor, latestMinor = major, minor
+		}
+	}
+	return latest
+}
+
+// headerTransport adds a fixed set of headers to every request before handing
+// it to the base RoundTripper.
+type headerTransport struct {
+	header http.Header
+	base   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.header) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the original request
+	req = req.Clone(req.Context())
+	for k, v := range t.header {
+		for _, val := range v {
+			req.Header.Add(k, val)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// parseOutboundHeaders turns key=value pairs into a http.Header. Values are
+// never logged as they usually carry credentials.
+func parseOutboundHeaders(pairs []string) http.Header {
+	header := make(http.Header)
+	for _, pair := range pairs {
+		key, value, ok := splitKeyValue(pair)
+		if !ok {
+			ErrorPrintHelpAndExit(&Options, fmt.Sprintf("Invalid outbound header \"%s\", expected key=value", strings.SplitN(pair, "=", 2)[0]))
+		}
+		header.Add(key, value)
+		glog.Infof("Outbound header %s: ***", http.CanonicalHeaderKey(key))
+	}
+	return header
+}
+
+// statsdClient aggregates counters, gauges and timers and flushes them
+// periodically to a StatsD server. All methods are no-ops on a nil client, so
+// callers don't have to check if StatsD is configured.
+type statsdClient struct {
+	mu       sync.Mutex
+	conn     net.Conn
+	prefix   string
+	counters map[string]int64
+	gauges   map[string]int64
+	timers   map[string][]time.Duration
+}
+
+func newStatsdClient(address string, prefix string) *statsdClient {
+	conn, err := net.Dial("udp", address)
+	CheckErrorFatal(err)
+	glog.Infof("Sending metrics to StatsD at %s", address)
+	return &statsdClient{
+		conn:     conn,
+		prefix:   prefix,
+		counters: make(map[string]int64),
+		gauges:   make(map[string]int64),
+		timers:   make(map[string][]time.Duration),
+	}
+}
+
+func (c *statsdClient) incr(name string, n int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.counters[name] += n
+	c.mu.Unlock()
+}
+
+func (c *statsdClient) gauge(name string, v int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.gauges[name] = v
+	c.mu.Unlock()
+}
+
+func (c *statsdClient) timing(name string, d time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.timers[name] = append(c.timers[name], d)
+	c.mu.Unlock()
+}
+
+func (c *statsdClient) flushLoop(interval time.Duration) {
+	for {
+		<-time.After(interval)
+		c.flush()
+	}
+}
+
+// flush sends all aggregated metrics and resets counters and timers. Lines
+// are batched into packets staying below the common 1432 byte UDP payload.
+func (c *statsdClient) flush() {
+	c.mu.Lock()
+	var lines []string
+	for name, v := range c.counters {
+		lines = append(lines, fmt.Sprintf("%s%s:%d|c", c.prefix, name, v))
+	}
+	for name, v := range c.gauges {
+		lines = append(lines, fmt.Sprintf("%s%s:%d|g", c.prefix, name, v))
+	}
+	for name, durations := range c.timers {
+		for _, d := range durations {
+			lines = append(lines, fmt.Sprintf("%s%s:%d|ms", c.prefix, name, d/time.Millisecond))
+		}
+	}
+	c.counters = make(map[string]int64)
+	c.timers = make(map[string][]time.Duration)
+	c.mu.Unlock()
+
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+len(line)+1 > 1432 {
+			c.send(packet)
+			packet = nil
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		c.send(packet)
+	}
+}
+
+func (c *statsdClient) send(packet []byte) {
+	if _, err := c.conn.Write(packet); err != nil {
+		glog.Warningf("Failed to send metrics to StatsD: %v", err)
+	}
+}
+
+// ParseArgs needs a struct compatible to jeddevdk/go-flags and will fill it
+// based on CLI parameters.
+func ParseArgs(options interface{}) {
+	err := parseOptions(options, os.Args, flags.Default)
+	if err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		} else {
+			panic(err)
+		}
+	}
+
+	fixGlog(options)
+	enforceMinRefresh(options)
+}
+
+// parseOptions fills options from the INI file given by --config, env and CLI
+// parameters. CLI parameters take precedence over the file, which takes
+// precedence over env and defaults.
+func parseOptions(options interface{}, args []string, parserOptions flags.Options) error {
+	var pre struct {
+		Config string `long:"config" env:"CONFIG_FILE"`
+	}
+	flags.NewParser(&pre, flags.IgnoreUnknown).ParseArgs(args)
+
+	parser := flags.NewParser(options, parserOptions)
+	var required []*flags.Option
+	if pre.Config != "" {
+		// go-flags doesn't count values of the file towards required
+		// options, those are checked after parsing
+		for _, g := range parser.Groups() {
+			for _, opt := range g.Options() {
+				if opt.Required {
+					opt.Required = false
+					required = append(required, opt)
+				}
+			}
+		}
+		if err := flags.NewIniParser(parser).ParseFile(pre.Config); err != nil {
+			return err
+		}
+	}
+	if _, err := parser.ParseArgs(args); err != nil {
+		return err
+	}
+	for _, opt := range required {
+		if reflect.ValueOf(opt.Value()).IsZero() {
+			return &flags.Error{Type: flags.ErrRequired, Message: fmt.Sprintf("the required flag `%s' was not specified", opt)}
+		}
+	}
+	return nil
+}
+
+// ErrorPrintHelpAndExit prints the message, the help message and exits
+func ErrorPrintHelpAndExit(options interface{}, message string) {
+	fmt.Fprintln(os.Stderr, message+"\n")
+	var parser = flags.NewParser(options, flags.Default)
+	parser.WriteHelp(os.Stderr)
+	os.Exit(1)
+}
+
+// configure glog, not used for flag parsing
+func fixGlog(options interface{}) {
+	flag.Set("logtostderr", "true")
+
+	verbose := reflect.ValueOf(options).Elem().FieldByName("Verbose")
+	if verbose.IsValid() {
+		flag.Set("v", strconv.Itoa(verbose.Interface().(int)))
+	}
+	flag.CommandLine.Parse([]string{})
+}
+
+// raise refresh intervals below MinRefresh to protect GitHub and Jenkins
+func enforceMinRefresh(options interface{}) {
+	v := reflect.ValueOf(options).Elem()
+	minRefresh := v.FieldByName("MinRefresh")
+	if !minRefresh.IsValid() {
+		return
+	}
+	minimum := minRefresh.Interface().(time.Duration)
+	for _, name := range []string{"GitHubOrgRefresh", "CiStatusRefresh"} {
+		field := v.FieldByName(name)
+		if field.IsValid() && field.Interface().(time.Duration) < minimum {
+			glog.Warningf("%s %s is below the minimum, using %s", name, field.Interface(), minimum)
+			field.Set(reflect.ValueOf(minimum))
+		}
 	}
-	flag.CommandLine.Parse([]string{})
 }
 
 // CheckErrorFatal to glog.Fatalf
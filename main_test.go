@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newGitHubMock serves the repos of the org o, page by page if there are
+// more than perPage.
+func newGitHubMock(t *testing.T, pages ...string) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/o/repos" {
+			http.NotFound(w, r)
+			return
+		}
+		page := 1
+		fmt.Sscanf(r.URL.Query().Get("page"), "%d", &page)
+		if page < 1 || page > len(pages) {
+			t.Errorf("Unexpected page %d requested", page)
+			http.NotFound(w, r)
+			return
+		}
+		if page < len(pages) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/o/repos?per_page=10&page=%d>; rel="next", <%s/orgs/o/repos?per_page=10&page=%d>; rel="last"`, srv.URL, page+1, srv.URL, len(pages)))
+		}
+		fmt.Fprint(w, pages[page-1])
+	}))
+	return srv
+}
+
+// newJenkinsMock answers buildStatus/text requests with the status of the
+// job, unknown jobs don't exist.
+func newJenkinsMock(status map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := status[r.URL.Query().Get("job")]
+		if r.URL.Path != "/buildStatus/text" || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, s)
+	}))
+}
+
+func repoJson(name string, archived bool) string {
+	return fmt.Sprintf(`{"name":"%s","archived":%t,"private":false,"default_branch":"master","html_url":"https://github.com/o/%s"}`, name, archived, name)
+}
+
+// setupOptions parses args like main does, pointed at the mocks, and
+// configures the package state from them.
+func setupOptions(t *testing.T, gitHub *httptest.Server, jenkins *httptest.Server, args ...string) {
+	Options = options{}
+	args = append([]string{"statuspage", "-p", "8080", "-t", "token", "-o", "o", "--ghapiurl", gitHub.URL, "--jenkinsurl", jenkins.URL}, args...)
+	if err := parseOptions(&Options, args, 0); err != nil {
+		t.Fatal(err)
+	}
+	configure()
+}
+
+func TestStatusPage(t *testing.T) {
+	gitHub := newGitHubMock(t, "["+strings.Join([]string{
+		repoJson("terraform-aws-vpc", false),
+		repoJson("terraform-gcp-network", false),
+	}, ",")+"]")
+	defer gitHub.Close()
+	jenkins := newJenkinsMock(map[string]string{
+		"dcos-terraform/terraform-aws-vpc/support%2F0.2.x":     "Success",
+		"dcos-terraform/terraform-aws-vpc/support%2F0.1.x":     "Failed",
+		"dcos-terraform/terraform-gcp-network/support%2F0.2.x": "Success",
+	})
+	defer jenkins.Close()
+	setupOptions(t, gitHub, jenkins, "--provider", "aws", "--provider", "gcp")
+
+	fetchRepositorys(Options.GitHubOrg)
+	fetchCiStatus([]string{"aws", "gcp"})
+	markdownContent()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "text/markdown")
+	handler(w, req)
+	md := w.Body.String()
+	for _, header := range []string{"# " + PAGE_TITLE, "### Provider: **aws**", "### Provider: **gcp**"} {
+		if !strings.Contains(md, header) {
+			t.Errorf("Markdown is missing %q:\n%s", header, md)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET / returned %d: %s", w.Code, w.Body.String())
+	}
+	page := w.Body.String()
+	for _, want := range []string{
+		"<title>" + PAGE_TITLE + "</title>",
+		"Provider: <strong>aws</strong>",
+		"Provider: <strong>gcp</strong>",
+		"terraform-aws-vpc",
+		"terraform-gcp-network",
+		"/static/images/1-build-passing.svg",
+		"/static/images/3-build-failing.svg",
+		"/static/images/0-build-notrun.svg",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Page is missing %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestDedupeBranches(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{"empty", []string{}, []string{}},
+		{"unique", []string{"support/0.2.x", "support/0.1.x", "master"}, []string{"support/0.2.x", "support/0.1.x", "master"}},
+		{"duplicates dropped", []string{"master", "master", "master"}, []string{"master"}},
+		{"first occurrence kept", []string{"support/0.1.x", "master", "support/0.1.x", "support/0.2.x", "master"}, []string{"support/0.1.x", "master", "support/0.2.x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dedupeBranches(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dedupeBranches(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchRepositorysPaginated(t *testing.T) {
+	first := []string{
+		repoJson("terraform-aws-vpc", false),
+		repoJson("terraform-aws-infrastructure", false),
+		repoJson("terraform-aws-legacy", true),
+		repoJson("terraform-azurerm-network", false),
+		repoJson("terraform-gcp-compute", false),
+		repoJson("terraform-aws-bootstrap", false),
+		repoJson("terraform-gcp-old", true),
+		repoJson("terraform-null-localfile", false),
+		repoJson("dcos-docs", false),
+		repoJson("terraform-gcp-network", false),
+	}
+	second := []string{
+		repoJson("terraform-template-cloudinit", false),
+		repoJson("terraform-aws-elb", false),
+		repoJson("terraform-azurerm-archived", true),
+	}
+	gitHub := newGitHubMock(t, "["+strings.Join(first, ",")+"]", "["+strings.Join(second, ",")+"]")
+	defer gitHub.Close()
+	jenkins := newJenkinsMock(nil)
+	defer jenkins.Close()
+	setupOptions(t, gitHub, jenkins)
+
+	tracked := fetchRepositorys(Options.GitHubOrg)
+	want := map[string][]string{
+		"aws":      {"terraform-aws-vpc", "terraform-aws-infrastructure", "terraform-aws-bootstrap", "terraform-aws-elb"},
+		"azurerm":  {"terraform-azurerm-network"},
+		"gcp":      {"terraform-gcp-compute", "terraform-gcp-network"},
+		"null":     {"terraform-null-localfile"},
+		"template": {"terraform-template-cloudinit"},
+	}
+	total := 0
+	for p, names := range want {
+		var got []string
+		for _, repo := range repos[p] {
+			got = append(got, repo.GetName())
+		}
+		if !reflect.DeepEqual(got, names) {
+			t.Errorf("repos[%q] = %q, want %q", p, got, names)
+		}
+		total += len(names)
+	}
+	if len(tracked) != total {
+		t.Errorf("fetchRepositorys returned %d repos, want %d", len(tracked), total)
+	}
+	for _, repo := range tracked {
+		if repo.GetArchived() {
+			t.Errorf("Archived repo %s is tracked", repo.GetName())
+		}
+	}
+}
+
+func TestBranchJenkinsUrls(t *testing.T) {
+	jenkinsUrl := "https://jenkins.example.com/service/jenkins"
+	optionsSnapshot.Store(&options{JenkinsUrl: jenkinsUrl})
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"support/0.2.x", "support/0.2.x"},
+		{"refs/heads/support/0.1.x", "support/0.1.x"},
+		{" master ", "master"},
+		{"feature/ünicode", "feature/ünicode"},
+		{"release/日本語", "release/日本語"},
+		{"fix#123", "fix#123"},
+		{"feature/100%", "feature/100%"},
+		{"a&b=c+d", "a&b=c+d"},
+		{"with space", ""},
+		{"tab\tbranch", ""},
+		{"what?", ""},
+		{"feature/what?now", ""},
+		{"bad\xffutf8", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			branches, err := resolveBranches([]string{tt.branch}, false)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("Branch %q accepted as %q, want an error", tt.branch, branches)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Branch %q rejected: %v", tt.branch, err)
+			}
+			if len(branches) != 1 || branches[0] != tt.want {
+				t.Fatalf("Branch %q resolved to %q, want %q", tt.branch, branches, tt.want)
+			}
+			branchHtmlDoubleEncoded := url.QueryEscape(url.QueryEscape(branches[0]))
+
+			status, err := url.Parse(jenkinsBuildStatusUrl("terraform-aws-vpc", branchHtmlDoubleEncoded))
+			if err != nil {
+				t.Fatalf("Build status URL of %q doesn't parse: %v", tt.branch, err)
+			}
+			job := status.Query().Get("job")
+			if !strings.HasPrefix(job, "dcos-terraform/terraform-aws-vpc/") {
+				t.Fatalf("Build status URL of %q has job %q", tt.branch, job)
+			}
+			if got, err := url.QueryUnescape(strings.TrimPrefix(job, "dcos-terraform/terraform-aws-vpc/")); err != nil || got != tt.want {
+				t.Errorf("Build status URL of %q round-trips to %q (%v)", tt.branch, got, err)
+			}
+
+			jobUrl, err := url.Parse(jenkinsJobUrl("terraform-aws-vpc", branchHtmlDoubleEncoded))
+			if err != nil {
+				t.Fatalf("Job URL of %q doesn't parse: %v", tt.branch, err)
+			}
+			prefix := "/service/jenkins/job/dcos-terraform/job/terraform-aws-vpc/job/"
+			if !strings.HasPrefix(jobUrl.Path, prefix) || !strings.HasSuffix(jobUrl.Path, "/") || jobUrl.RawQuery != "" || jobUrl.Fragment != "" {
+				t.Fatalf("Job URL of %q is %s", tt.branch, jobUrl)
+			}
+			segment := strings.TrimSuffix(strings.TrimPrefix(jobUrl.Path, prefix), "/")
+			if got, err := url.QueryUnescape(segment); err != nil || got != tt.want {
+				t.Errorf("Job URL of %q round-trips to %q (%v)", tt.branch, got, err)
+			}
+		})
+	}
+}
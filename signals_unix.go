@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// shutdownSignals trigger a graceful shutdown
+var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+)
+
+// shutdownSignals trigger a graceful shutdown, Windows only delivers
+// os.Interrupt (Ctrl+C and Ctrl+Break).
+var shutdownSignals = []os.Signal{os.Interrupt}